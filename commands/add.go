@@ -3,12 +3,15 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"text/template"
 
 	_ "embed"
 
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/rudderlabs/git-hooks/internal/buildinfo"
+	"github.com/rudderlabs/git-hooks/internal/provision"
+	"github.com/rudderlabs/git-hooks/internal/shim"
 	"github.com/urfave/cli/v2"
 )
 
@@ -18,6 +21,32 @@ var gitLeaksScriptTemplate string
 //go:embed hooks/gitleaks/commit-msg.sh
 var gitLeaksCommitMsgTemplate string
 
+//go:embed hooks/gitleaks/post-commit.sh
+var gitLeaksPostCommitTemplate string
+
+//go:embed hooks/gitleaks/pre-push.sh
+var gitLeaksPrePushTemplate string
+
+//go:embed hooks/gitleaks/pre-merge-commit.sh
+var gitLeaksPreMergeCommitTemplate string
+
+//go:embed hooks/gitleaks/pre-applypatch.sh
+var gitLeaksPreApplyPatchTemplate string
+
+//go:embed hooks/gitleaks/post-rewrite.sh
+var gitLeaksPostRewriteTemplate string
+
+// gitleaksScripts are the hooks `add gitleaks` installs a script for
+var gitleaksScripts = []struct{ hook, template string }{
+	{"pre-commit", gitLeaksScriptTemplate},
+	{"commit-msg", gitLeaksCommitMsgTemplate},
+	{"post-commit", gitLeaksPostCommitTemplate},
+	{"pre-push", gitLeaksPrePushTemplate},
+	{"pre-merge-commit", gitLeaksPreMergeCommitTemplate},
+	{"pre-applypatch", gitLeaksPreApplyPatchTemplate},
+	{"post-rewrite", gitLeaksPostRewriteTemplate},
+}
+
 var Add = &cli.Command{
 	Name:  "add",
 	Usage: "adds a new git hook",
@@ -25,121 +54,97 @@ var Add = &cli.Command{
 		{
 			Name:  "gitleaks",
 			Usage: "pre-commit hook to run gitleaks detect",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "version",
+					Usage: "gitleaks release to install (default: githooks.gitleaks.version or " + provision.DefaultVersion + ")",
+				},
+				&cli.StringFlag{
+					Name:  "mirror",
+					Usage: "base URL of the gitleaks releases (default: githooks.gitleaks.mirror or GitHub)",
+				},
+				&cli.StringFlag{
+					Name:  "from-archive",
+					Usage: "install from a downloaded release archive instead, offline",
+				},
+				HomeFlag,
+			},
 			Action: func(c *cli.Context) error {
-				return addGitLeaks()
+				hooksDir, err := hooksHome(c)
+				if err != nil {
+					return err
+				}
+				opts, err := provision.FromConfig(".", provision.Options{
+					Version:  c.String("version"),
+					Mirror:   c.String("mirror"),
+					Archive:  c.String("from-archive"),
+					CacheDir: provision.CacheDir(hooksDir),
+					Log:      os.Stdout,
+				})
+				if err != nil {
+					return err
+				}
+				return addGitLeaks(hooksDir, opts)
 			},
 		},
 	},
 }
 
-func addGitLeaks() error {
-	// Ensure gitleaks is installed and up-to-date
-	gitleaksPath, err := installLatestGitleaks()
-	if err != nil {
-		return fmt.Errorf("installing/updating gitleaks: %w", err)
-	}
-
-	hooksDir := filepath.Join(os.Getenv("HOME"), ".git-hooks", "pre-commit.d")
-
-	// Create the pre-commit.d directory if it doesn't exist
-	err = os.MkdirAll(hooksDir, 0o755)
-	if err != nil {
-		return fmt.Errorf("creating pre-commit.d directory: %w", err)
-	}
-
-	// Create the gitleaks script using the template
-	scriptPath := filepath.Join(hooksDir, "gitleaks")
-
-	tmpl, err := template.New("gitleaks").Parse(gitLeaksScriptTemplate)
-	if err != nil {
-		return fmt.Errorf("parsing gitleaks script template: %w", err)
-	}
-
-	file, err := os.Create(scriptPath)
-	if err != nil {
-		return fmt.Errorf("creating gitleaks script file: %w", err)
-	}
-	defer file.Close()
-
-	err = tmpl.Execute(file, map[string]string{"GitleaksPath": gitleaksPath})
+func addGitLeaks(hooksHome string, opts provision.Options) error {
+	// Install the pinned gitleaks release, verified against its checksum
+	gitleaksPath, err := provision.Gitleaks(opts)
 	if err != nil {
-		return fmt.Errorf("executing gitleaks script template: %w", err)
+		return fmt.Errorf("installing gitleaks: %w", err)
 	}
 
-	err = os.Chmod(scriptPath, 0o755)
+	// The scripts call back into this binary
+	params, err := shim.Current(buildinfo.Version())
 	if err != nil {
-		return fmt.Errorf("setting permissions for gitleaks script: %w", err)
+		return err
 	}
 
-	fmt.Printf("Gitleaks pre-commit hook installed at: %s\n", scriptPath)
-
-	// Install commit-msg hook
-	commitMsgDir := filepath.Join(os.Getenv("HOME"), ".git-hooks", "commit-msg.d")
-	err = os.MkdirAll(commitMsgDir, 0o755)
-	if err != nil {
-		return fmt.Errorf("creating commit-msg.d directory: %w", err)
+	templateData := map[string]string{
+		"GitleaksPath":    gitleaksPath,
+		"GitleaksVersion": gitleaks.Version(gitleaksPath),
+		"GitleaksCLI":     string(gitleaks.DetectCLI(gitleaksPath)),
+		"BinaryPath":      params.BinaryPath,
 	}
 
-	commitMsgPath := filepath.Join(commitMsgDir, "gitleaks")
-
-	tmpl2, err := template.New("gitleaks-commit-msg").Parse(gitLeaksCommitMsgTemplate)
-	if err != nil {
-		return fmt.Errorf("parsing gitleaks commit-msg template: %w", err)
+	for _, script := range gitleaksScripts {
+		path, err := installGitleaksScript(hooksHome, script.hook, script.template, templateData)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Gitleaks %s hook installed at: %s\n", script.hook, path)
 	}
+	return nil
+}
 
-	file2, err := os.Create(commitMsgPath)
-	if err != nil {
-		return fmt.Errorf("creating gitleaks commit-msg file: %w", err)
+// installGitleaksScript writes the gitleaks script for hook into its
+// directory in the hooks home
+func installGitleaksScript(hooksHome, hook, text string, data map[string]string) (string, error) {
+	hooksDir := filepath.Join(hooksHome, hook+".d")
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating %s.d directory: %w", hook, err)
 	}
-	defer file2.Close()
 
-	err = tmpl2.Execute(file2, map[string]string{"GitleaksPath": gitleaksPath})
+	tmpl, err := template.New("gitleaks-" + hook).Parse(text)
 	if err != nil {
-		return fmt.Errorf("executing gitleaks commit-msg template: %w", err)
+		return "", fmt.Errorf("parsing gitleaks %s template: %w", hook, err)
 	}
 
-	err = os.Chmod(commitMsgPath, 0o755)
+	scriptPath := filepath.Join(hooksDir, "gitleaks")
+	file, err := os.Create(scriptPath)
 	if err != nil {
-		return fmt.Errorf("setting permissions for gitleaks commit-msg script: %w", err)
+		return "", fmt.Errorf("creating gitleaks %s file: %w", hook, err)
 	}
+	defer file.Close()
 
-	fmt.Printf("Gitleaks commit-msg hook installed at: %s\n", commitMsgPath)
-
-	return nil
-}
-
-func installLatestGitleaks() (string, error) {
-	// Check if Homebrew is available
-	_, err := exec.LookPath("brew")
-	if err == nil {
-		// Homebrew is available, use it to install Gitleaks
-		cmd := exec.Command("brew", "install", "gitleaks")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		fmt.Println("Installing/updating gitleaks using Homebrew...")
-		if err := cmd.Run(); err != nil {
-			return "", fmt.Errorf("installing/updating gitleaks with Homebrew: %w", err)
-		}
-	} else {
-		// Homebrew is not available, fall back to go install
-		cmd := exec.Command("go", "install", "github.com/zricethezav/gitleaks/v8@latest")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		fmt.Println("Installing/updating gitleaks using go install...")
-		if err := cmd.Run(); err != nil {
-			return "", fmt.Errorf("installing/updating gitleaks with go install: %w", err)
-		}
+	if err := tmpl.Execute(file, data); err != nil {
+		return "", fmt.Errorf("executing gitleaks %s template: %w", hook, err)
 	}
-
-	fmt.Println("Gitleaks installed/updated successfully.")
-
-	// Find the full path of gitleaks
-	gitleaksPath, err := exec.LookPath("gitleaks")
-	if err != nil {
-		return "", fmt.Errorf("finding gitleaks in PATH: %w", err)
+	if err := os.Chmod(scriptPath, 0o755); err != nil {
+		return "", fmt.Errorf("setting permissions for gitleaks %s script: %w", hook, err)
 	}
-
-	return gitleaksPath, nil
+	return scriptPath, nil
 }
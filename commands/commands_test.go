@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+// TestMain lets shims exec the test binary as git-hooks: `hook` runs the
+// command instead of the tests
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == Hooks.Name {
+		app := &cli.App{Name: "git-hooks", Flags: []cli.Flag{HomeFlag}, Commands: []*cli.Command{Hooks}}
+		if err := app.Run(os.Args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// Helper functions
+
+// setupHome points HOME at a temp directory and isolates git from the real
+// user and system configuration. Commands run in the new home.
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_HOOKS_HOME", "")
+	t.Chdir(home)
+	return home
+}
+
+// run runs git-hooks with args and returns what it printed
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	app := &cli.App{
+		Name:     "git-hooks",
+		Flags:    []cli.Flag{HomeFlag},
+		Commands: []*cli.Command{Config, Implode},
+		// Errors are returned to the test instead of exiting
+		ExitErrHandler: func(*cli.Context, error) {},
+	}
+	err = app.Run(append([]string{"git-hooks"}, args...))
+
+	os.Stdout = stdout
+	require.NoError(t, w.Close())
+	return <-output, err
+}
+
+// gitConfig returns the values of key in scope, e.g. --global
+func gitConfig(t *testing.T, scope, key string) []string {
+	t.Helper()
+
+	output, err := exec.Command("git", "config", scope, "--get-all", key).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(output)), "\n")
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+}
+
+// writeScript creates an executable shell script
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+}
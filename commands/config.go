@@ -1,47 +1,45 @@
 package commands
 
 import (
-	"embed"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"text/template"
+	"strings"
 
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/plural"
+	"github.com/rudderlabs/git-hooks/internal/shim"
 	"github.com/urfave/cli/v2"
 )
 
-//go:embed hook.sh
-var hookTemplate embed.FS
-
 var Config = &cli.Command{
-	Name:  "config",
-	Usage: "Configure git-hooks",
-	Action: func(c *cli.Context) error {
-		return configureGitHooks()
-	},
-}
-
-var Implode = &cli.Command{
-	Name:  "implode",
-	Usage: "Revert git-hooks configuration",
+	Name:      "config",
+	Usage:     "Configure git-hooks",
+	ArgsUsage: "[REPO]",
+	Flags: append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:  "repair",
+			Usage: "Rewrite hook shims that point at a missing or outdated git-hooks binary",
+		},
+	}, installFlags()...),
 	Action: func(c *cli.Context) error {
-		return implodeGitHooks()
+		inst, err := resolveInstallation(c)
+		if err != nil {
+			return err
+		}
+		if c.Bool("repair") {
+			return repairGitHooks(inst)
+		}
+		return configureGitHooks(inst)
 	},
 }
 
-var gitHooks = []string{
-	"applypatch-msg", "pre-applypatch", "post-applypatch",
-	"pre-commit", "pre-merge-commit", "prepare-commit-msg", "commit-msg", "post-commit",
-	"pre-rebase", "post-checkout", "post-merge", "pre-push", "pre-receive",
-	"update", "proc-receive", "post-receive", "post-update", "reference-transaction",
-	"push-to-checkout", "pre-auto-gc", "post-rewrite", "sendemail-validate",
-	"fsmonitor-watchman", "p4-changelist", "p4-prepare-changelist", "p4-post-changelist", "p4-pre-submit",
-	"post-index-change",
-}
+// previousHooksPathKey holds the core.hooksPath that was set in the same
+// scope before git-hooks took over
+const previousHooksPathKey = "githooks.previousHooksPath"
 
-func configureGitHooks() error {
-	hooksDir := filepath.Join(os.Getenv("HOME"), ".git-hooks")
+func configureGitHooks(inst installation) error {
+	hooksDir := inst.dir
 
 	// Create the directory if it doesn't exist
 	err := os.MkdirAll(hooksDir, 0o755)
@@ -49,128 +47,114 @@ func configureGitHooks() error {
 		return fmt.Errorf("creating hooks directory: %w", err)
 	}
 
-	gitHooksPath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("finding executable path: %w", err)
-	}
-
-	// Resolve symlinks to get the actual binary path
-	gitHooksPath, err = filepath.EvalSymlinks(gitHooksPath)
+	params, err := inst.params()
 	if err != nil {
-		return fmt.Errorf("resolving executable symlinks: %w", err)
+		return err
 	}
 
-	fmt.Printf("Using git-hooks binary: %s\n", gitHooksPath)
-
-	// Parse the embedded template
-	tmpl, err := template.ParseFS(hookTemplate, "hook.sh")
-	if err != nil {
-		return fmt.Errorf("parsing hook template: %w", err)
-	}
+	fmt.Printf("Using git-hooks binary: %s (%s)\n", params.BinaryPath, params.Version)
 
 	// Create a script for each Git hook
-	for _, hook := range gitHooks {
-		if err := createHookScript(hooksDir, hook, tmpl, gitHooksPath); err != nil {
+	counts := map[shim.Action]int{}
+	for _, hook := range shim.Hooks {
+		change, err := shim.Install(hooksDir, hook, params)
+		if err != nil {
 			return err
 		}
+		counts[change.Action]++
+		if change.MigratedTo != "" {
+			fmt.Printf("Moved existing %s hook to: %s\n", hook, change.MigratedTo)
+		}
 	}
 
-	// Configure Git to use the directory
-	cmd := exec.Command("git", "config", "--global", "core.hooksPath", hooksDir)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("configuring Git hooks: %w", err)
+	if len(inst.scopeDirs) > 0 {
+		if err := configureScopes(inst); err != nil {
+			return err
+		}
+	} else if err := configureHooksPath(inst); err != nil {
+		return err
 	}
 
-	fmt.Printf("Git hooks configured to use directory: %s\n", hooksDir)
-	fmt.Println("Scripts created for all Git hooks")
+	fmt.Printf("Git hooks configured to use directory: %s\n", inst)
+	fmt.Printf("Hook scripts: %d created, %d updated, %d unchanged\n",
+		counts[shim.ActionCreated], counts[shim.ActionUpdated], counts[shim.ActionUnchanged])
 	return nil
 }
 
-func implodeGitHooks() error {
-	hooksDir := filepath.Join(os.Getenv("HOME"), ".git-hooks")
+// configureHooksPath points core.hooksPath at the shims in the installation's
+// config scope
+func configureHooksPath(inst installation) error {
+	hooksDir := inst.dir
 
-	// Count and collect names of files not created by the tool
-	fileCount := 0
-	var filesToDelete []string
-	err := filepath.Walk(hooksDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			hookName := filepath.Base(path)
-			if !contains(gitHooks, hookName) {
-				fileCount++
-				filesToDelete = append(filesToDelete, path)
-			}
-		}
-		return nil
-	})
+	// Remember a previous hooksPath so implode can restore it
+	previous, ok, err := gitconfig.Get(inst.configDir, inst.scope, "core.hooksPath")
 	if err != nil {
-		return fmt.Errorf("analyzing git-hooks directory: %w", err)
+		return fmt.Errorf("reading current Git hooks configuration: %w", err)
 	}
-
-	// Ask for user confirmation
-	if fileCount > 0 {
-		fmt.Printf("This will delete %d file(s) in %s:\n", fileCount, hooksDir)
-		for _, file := range filesToDelete {
-			fmt.Printf("  - %s\n", filepath.Base(file))
-		}
-		fmt.Print("Are you sure you want to proceed? (y/N): ")
-		var response string
-		_, err = fmt.Scanln(&response)
-		if err != nil {
-			return fmt.Errorf("reading user input: %w", err)
+	if ok && filepath.Clean(previous) != filepath.Clean(hooksDir) {
+		if err := gitconfig.Set(inst.configDir, inst.scope, previousHooksPathKey, previous); err != nil {
+			return fmt.Errorf("saving previous Git hooks configuration: %w", err)
 		}
+		fmt.Printf("Saved previous core.hooksPath %s, implode will restore it\n", previous)
+	}
 
-		if response != "y" && response != "Y" {
-			fmt.Println("Operation cancelled.")
-			return nil
-		}
-	} else {
-		fmt.Println("No additional files found in the git-hooks directory.")
+	// Configure Git to use the directory
+	if err := gitconfig.Set(inst.configDir, inst.scope, "core.hooksPath", hooksDir); err != nil {
+		return fmt.Errorf("configuring Git hooks: %w", err)
 	}
+	return nil
+}
 
-	// Remove the git-hooks directory
-	err = os.RemoveAll(hooksDir)
+// configureScopes sets core.hooksPath in an include file that is only loaded
+// for repositories below the scope directories
+func configureScopes(inst installation) error {
+	existing, err := scopedDirs(inst.dir)
 	if err != nil {
-		return fmt.Errorf("removing git-hooks directory: %w", err)
+		return fmt.Errorf("reading git-hooks scopes: %w", err)
+	}
+	dirs := existing
+	for _, dir := range inst.scopeDirs {
+		if !contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
 	}
 
-	// Reset Git's core.hooksPath configuration
-	cmd := exec.Command("git", "config", "--global", "--unset", "core.hooksPath")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("unsetting Git hooks configuration: %w", err)
+	if err := addScopes(inst.dir, dirs); err != nil {
+		return err
 	}
 
-	fmt.Println("Git hooks configuration has been reverted.")
-	fmt.Printf("Removed directory: %s\n", hooksDir)
-	fmt.Println("Reset Git's core.hooksPath configuration")
+	// A global hooksPath would activate git-hooks everywhere
+	current, ok, err := gitconfig.Get(inst.configDir, gitconfig.ScopeGlobal, "core.hooksPath")
+	if err != nil {
+		return fmt.Errorf("reading current Git hooks configuration: %w", err)
+	}
+	if ok && filepath.Clean(current) == filepath.Clean(inst.dir) {
+		if err := restoreHooksPath(inst); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func createHookScript(hooksDir, hook string, tmpl *template.Template, gitHooksPath string) error {
-	scriptPath := filepath.Join(hooksDir, hook)
-	file, err := os.Create(scriptPath)
+func repairGitHooks(inst installation) error {
+	params, err := inst.params()
 	if err != nil {
-		return fmt.Errorf("creating script file for %s: %w", hook, err)
+		return err
 	}
-	defer file.Close()
 
-	err = tmpl.Execute(file, struct {
-		GitHooksPath string
-		HookName     string
-	}{
-		GitHooksPath: gitHooksPath,
-		HookName:     hook,
-	})
+	repaired, err := shim.Repair(inst.dir, shim.Hooks, params)
 	if err != nil {
-		return fmt.Errorf("executing template for %s: %w", hook, err)
+		return fmt.Errorf("repairing hook shims: %w", err)
 	}
 
-	if err := file.Chmod(0o755); err != nil {
-		return fmt.Errorf("setting permissions for %s: %w", hook, err)
+	if len(repaired) == 0 {
+		fmt.Println("All hook shims are up to date.")
+		return nil
 	}
 
+	fmt.Printf("Repaired %d hook %s to use %s (%s):\n",
+		len(repaired), plural.Of(len(repaired), "shim", "shims"), params.BinaryPath, params.Version)
+	fmt.Printf("  %s\n", strings.Join(repaired, ", "))
 	return nil
 }
 
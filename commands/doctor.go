@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rudderlabs/git-hooks/internal/buildinfo"
+	"github.com/rudderlabs/git-hooks/internal/doctor"
+	"github.com/rudderlabs/git-hooks/internal/shim"
+	"github.com/urfave/cli/v2"
+)
+
+var Doctor = &cli.Command{
+	Name:  "doctor",
+	Usage: "Diagnose the git-hooks setup for the current repository",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "fix",
+			Usage: "Apply safe fixes for failing checks",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the report as JSON",
+		},
+		HomeFlag,
+	},
+	Action: doctorAction,
+}
+
+func doctorAction(c *cli.Context) error {
+	hooksDir, err := hooksHome(c)
+	if err != nil {
+		return err
+	}
+
+	params, err := shim.Current(buildinfo.Version())
+	if err != nil {
+		return err
+	}
+	params.Home = hooksDir
+
+	scopes, err := scopedDirs(hooksDir)
+	if err != nil {
+		return fmt.Errorf("reading git-hooks scopes: %w", err)
+	}
+
+	report := doctor.Run(doctor.Options{
+		HooksDir:  hooksDir,
+		SystemDir: defaultSystemDir,
+		ScopeFile: scopeFile(hooksDir),
+		ScopeDirs: scopes,
+		Hooks:     shim.Hooks,
+		Params:    params,
+		RepoDir:   ".",
+		Fix:       c.Bool("fix"),
+	})
+
+	if c.Bool("json") {
+		encoder := json.NewEncoder(os.Stdout) //nolint:forbidigo
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("encoding report: %w", err)
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if report.Failed() {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/urfave/cli/v2"
+)
+
+// gitleaksFlags describe the gitleaks binary as `add gitleaks` found it
+var gitleaksFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "gitleaks-path",
+		Usage: "gitleaks binary to run (default: gitleaks in PATH)",
+	},
+	&cli.StringFlag{
+		Name:  "gitleaks-version",
+		Usage: "gitleaks version the hook was installed with, to warn when the binary changed",
+	},
+	&cli.StringFlag{
+		Name:  "gitleaks-cli",
+		Usage: "gitleaks commands to use: git (8.19 and later) or protect (default: detected)",
+	},
+	HomeFlag,
+}
+
+var Gitleaks = &cli.Command{
+	Name:  "gitleaks",
+	Usage: "Built-in gitleaks hooks, called by the scripts `add gitleaks` installs",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "pre-commit",
+			Usage: "Scan the staged changes",
+			Flags: gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				return gitleaks.PreCommit(gitleaksHook(c))
+			}),
+		},
+		{
+			Name:      "commit-msg",
+			Usage:     "Scan a commit message for secrets and append the Scanned-by trailer",
+			ArgsUsage: "FILE",
+			Flags:     gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return fmt.Errorf("expected the commit message file")
+				}
+				return gitleaks.CommitMsg(gitleaksHook(c), c.Args().First())
+			}),
+		},
+		{
+			Name:  "post-commit",
+			Usage: "Record the scan of the new commit in refs/notes/git-hooks, or scan it when it bypassed pre-commit",
+			Flags: gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				return gitleaks.PostCommit(gitleaksHook(c))
+			}),
+		},
+		{
+			Name:      "pre-push",
+			Usage:     "Scan the commits being pushed, read from stdin like the pre-push hook",
+			ArgsUsage: "[REMOTE [URL]]",
+			Flags:     gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				remote := "origin"
+				if c.NArg() > 0 {
+					remote = c.Args().First()
+				}
+				return gitleaks.PrePush(gitleaksHook(c), remote, os.Stdin)
+			}),
+		},
+		{
+			Name:  "pre-merge-commit",
+			Usage: "Scan the result of a merge before it is committed",
+			Flags: gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				return gitleaks.PreMergeCommit(gitleaksHook(c))
+			}),
+		},
+		{
+			Name:  "pre-applypatch",
+			Usage: "Scan a patch applied by git am before it is committed",
+			Flags: gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				return gitleaks.PreApplyPatch(gitleaksHook(c))
+			}),
+		},
+		{
+			Name:      "post-rewrite",
+			Usage:     "Scan the commits created by rebase, read from stdin like the post-rewrite hook",
+			ArgsUsage: "amend|rebase",
+			Flags:     gitleaksFlags,
+			Action: scanAction(func(c *cli.Context) error {
+				command := c.Args().First()
+				if command == "" {
+					command = "rebase"
+				}
+				return gitleaks.PostRewrite(gitleaksHook(c), command, os.Stdin)
+			}),
+		},
+	},
+}
+
+// scanAction exits with status 1 without further message when gitleaks found
+// secrets, the hook already reported them
+func scanAction(action cli.ActionFunc) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		err := action(c)
+		if errors.Is(err, gitleaks.ErrLeaks) {
+			return cli.Exit("", 1)
+		}
+		return err
+	}
+}
+
+func gitleaksHook(c *cli.Context) gitleaks.Hook {
+	path := c.String("gitleaks-path")
+	if path == "" {
+		path, _ = exec.LookPath("gitleaks")
+	}
+	// Without a home there is just no global config to layer on
+	hooksDir, _ := hooksHome(c)
+	return gitleaks.Hook{
+		GitleaksPath: path,
+		HooksHome:    hooksDir,
+		Version:      c.String("gitleaks-version"),
+		CLI:          gitleaks.CLI(c.String("gitleaks-cli")),
+		RepoDir:      ".",
+		Stdout:       os.Stdout,
+		Stderr:       os.Stderr,
+	}
+}
@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"github.com/rudderlabs/git-hooks/internal/home"
+	"github.com/urfave/cli/v2"
+)
+
+var HomeFlag = &cli.StringFlag{
+	Name:  "home",
+	Usage: "git-hooks home directory (default: $" + home.EnvVar + ", ~/.git-hooks or $XDG_CONFIG_HOME/git-hooks)",
+}
+
+// hooksHome resolves the git-hooks home directory for the current invocation.
+// --home is accepted before and after the command name.
+func hooksHome(c *cli.Context) (string, error) {
+	for _, ctx := range c.Lineage() {
+		if ctx.IsSet(HomeFlag.Name) {
+			return home.Resolve(ctx.String(HomeFlag.Name))
+		}
+	}
+	return home.Resolve("")
+}
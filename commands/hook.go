@@ -1,125 +1,108 @@
 package commands
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 
+	"github.com/rudderlabs/git-hooks/internal/buildinfo"
+	"github.com/rudderlabs/git-hooks/internal/dispatch"
+	"github.com/rudderlabs/git-hooks/internal/plural"
+	"github.com/rudderlabs/git-hooks/internal/shim"
 	"github.com/urfave/cli/v2"
 )
 
 var Hooks = &cli.Command{
 	Name:  "hook",
 	Usage: "",
+	Flags: []cli.Flag{HomeFlag},
 	Action: func(c *cli.Context) error {
 		if c.NArg() == 0 {
 			return cli.ShowAppHelp(c)
 		}
 
+		hooksDir, err := hooksHome(c)
+		if err != nil {
+			return err
+		}
+
 		hookName := c.Args().First()
-		return executeHook(hookName)
+		refreshStaleShims(hooksDir, hookName)
+		return executeHook(hooksDir, hookName, c.Args().Tail())
 	},
 }
 
-func executeHook(hookName string) error {
-	// 1. Execute global scripts
-	err := executeScriptsInDir(filepath.Join(os.Getenv("HOME"), ".git-hooks", hookName+".d"))
-	if err != nil {
-		return err
+func executeHook(hooksDir, hookName string, args []string) error {
+	gitDir := os.Getenv("GIT_DIR")
+	if gitDir == "" {
+		gitDir = ".git"
 	}
 
-	// 2. Execute local scripts
-	err = executeScriptsInDir(filepath.Join(".git-hooks", hookName+".d"))
-	if err != nil {
-		return err
+	hook := dispatch.Hook{
+		Name:      hookName,
+		Args:      args,
+		HooksDir:  hooksDir,
+		SystemDir: os.Getenv("GIT_HOOKS_SYSTEM_DIR"),
+		RepoDir:   ".",
+		GitDir:    gitDir,
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
 	}
 
-	// 3. Execute Husky scripts (try both modern and legacy formats)
-	err = executeHuskyGitHooks(hookName)
+	scripts, err := dispatch.Collect(hook)
 	if err != nil {
 		return err
 	}
-
-	// 4. Execute standard Git hook for backwards compatibility
-	gitDir := os.Getenv("GIT_DIR")
-	if gitDir == "" {
-		gitDir = ".git"
-	}
-	return executeStandardGitHook(filepath.Join(gitDir, "hooks", hookName))
+	return dispatch.Run(hook, scripts)
 }
 
-func executeScriptsInDir(dir string) error {
-	files, err := os.ReadDir(dir)
+// refreshStaleShims rewrites the installed shims when the shim that invoked
+// this hook points at a different binary or version. Failures are reported
+// but never block the hook.
+func refreshStaleShims(hooksDir, hookName string) {
+	params, err := shim.Current(buildinfo.Version())
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // Directory doesn't exist, which is fine
-		}
-		return err
+		return
 	}
 
-	for _, file := range files {
-		if !file.IsDir() {
-			scriptPath := filepath.Join(dir, file.Name())
-			if err := executeScript(scriptPath); err != nil {
-				return err
-			}
-		}
+	shimPath := os.Getenv("GIT_HOOKS_SHIM")
+	if shimPath == "" {
+		// Shims written before version tracking don't export their path
+		shimPath = filepath.Join(hooksDir, hookName)
 	}
 
-	return nil
-}
-
-func executeScript(path string) error {
-	// Skip binary name (os.Args[0]), "hook" subcommand (os.Args[1]), and hook name (os.Args[2])
-	// Pass only the actual hook arguments starting from os.Args[3]
-	var args []string
-	if len(os.Args) > 3 {
-		args = os.Args[3:]
+	info, err := shim.Read(shimPath)
+	if err != nil || !info.Stale(params) {
+		return
 	}
-	cmd := exec.Command(path, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = os.Environ()
-	return cmd.Run()
-}
 
-func executeStandardGitHook(path string) error {
-	if info, err := os.Stat(path); err == nil {
-		// Check if it's a regular file and executable
-		if info.Mode().IsRegular() && (info.Mode()&0o111) != 0 {
-			return executeScript(path)
-		}
-		// If it exists but is not executable, skip silently
-		return nil
+	// Legacy shims always exec their recorded binary, so a mismatch there means
+	// git-hooks was invoked by hand rather than through the shim
+	if os.Getenv("GIT_HOOKS_SHIM") == "" && !shim.SameBinary(info.BinaryPath, params.BinaryPath) {
+		return
 	}
-	return nil // Hook doesn't exist, which is fine
-}
 
-func executeHuskyGitHooks(hookName string) error {
-	// Try modern Husky format first (.husky/hookname)
-	modernPath := filepath.Join(".husky", hookName)
-	if err := executeHuskyGitHook(modernPath); err != nil {
-		return err
+	shimDir := filepath.Dir(shimPath)
+	if systemDir := os.Getenv("GIT_HOOKS_SYSTEM_DIR"); systemDir != "" && filepath.Clean(systemDir) == filepath.Clean(shimDir) {
+		params.SystemDir = systemDir
+	} else {
+		params.Home = hooksDir
 	}
 
-	// Try legacy Husky format (.husky/_/hookname)
-	legacyPath := filepath.Join(".husky/_", hookName)
-	if err := executeHuskyGitHook(legacyPath); err != nil {
-		return err
+	repaired, err := shim.Repair(shimDir, shim.Hooks, params)
+	if errors.Is(err, fs.ErrPermission) {
+		// System-wide shims are owned by root, the PATH fallback keeps them working
+		return
 	}
-
-	return nil
-}
-
-func executeHuskyGitHook(path string) error {
-	if info, err := os.Stat(path); err == nil {
-		// Check if it's a regular file and executable
-		if info.Mode().IsRegular() && (info.Mode()&0o111) != 0 {
-			return executeScript(path)
-		}
-		// If it exists but is not executable, skip silently
-		return nil
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "git-hooks: refreshing stale hook shims: %v\n", err)
+		return
+	}
+	if len(repaired) > 0 {
+		fmt.Fprintf(os.Stderr, "git-hooks: refreshed %d hook %s in %s to use %s (%s)\n",
+			len(repaired), plural.Of(len(repaired), "shim", "shims"), shimDir, params.BinaryPath, params.Version)
 	}
-	return nil // Hook doesn't exist, which is fine
 }
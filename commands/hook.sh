@@ -1,3 +0,0 @@
-#!/bin/sh
-# Run git-hooks with the full path
-"{{.GitHooksPath}}" hook {{.HookName}} "$@"
\ No newline at end of file
@@ -0,0 +1,80 @@
+package gitleaks
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CLI is the command set of a gitleaks release
+type CLI string
+
+const (
+	// CLIGit is gitleaks 8.19 and later, with the git, dir and stdin commands
+	CLIGit CLI = "git"
+	// CLIProtect is older gitleaks, with the protect and detect commands
+	CLIProtect CLI = "protect"
+)
+
+// DetectCLI probes the commands gitleaks at gitleaksPath supports
+func DetectCLI(gitleaksPath string) CLI {
+	if err := exec.Command(gitleaksPath, "git", "--help").Run(); err == nil {
+		return CLIGit
+	}
+	return CLIProtect
+}
+
+// Staged returns the arguments scanning the staged changes
+func (c CLI) Staged() []string {
+	if c == CLIGit {
+		return []string{"git", "--no-banner", "--staged"}
+	}
+	return []string{"protect", "--no-banner", "--staged"}
+}
+
+// Log returns the arguments scanning the commits git log selects with
+// logOpts
+func (c CLI) Log(logOpts string) []string {
+	if c == CLIGit {
+		return []string{"git", "--no-banner", "--log-opts", logOpts}
+	}
+	return []string{"detect", "--no-banner", "--log-opts", logOpts}
+}
+
+// Dir returns the arguments scanning the files in dir, outside of git
+func (c CLI) Dir(dir string) []string {
+	if c == CLIGit {
+		return []string{"dir", "--no-banner", dir}
+	}
+	return []string{"detect", "--no-banner", "--no-git", "--source", dir}
+}
+
+// checkVersion warns when the gitleaks binary changed version since the hook
+// was installed, and picks the commands of the binary that runs
+func (h *Hook) checkVersion() {
+	if h.Version != "" {
+		if current := Version(h.GitleaksPath); current != h.Version {
+			if current == "" {
+				current = "unknown"
+			}
+			fmt.Fprintf(h.Stderr, "Warning: gitleaks at %s is version %s, the hook was installed with %s. Run `git-hooks add gitleaks` to update the hook.\n",
+				h.GitleaksPath, current, h.Version)
+			h.CLI = ""
+		}
+	}
+	if h.CLI == "" {
+		h.CLI = DetectCLI(h.GitleaksPath)
+	}
+}
+
+// command returns the gitleaks command line for args, shown to users
+func (h Hook) command(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		quoted[i] = arg
+	}
+	return h.GitleaksPath + " " + strings.Join(quoted, " ")
+}
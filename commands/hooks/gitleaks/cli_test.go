@@ -0,0 +1,115 @@
+package gitleaks_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/rudderlabs/git-hooks/internal/attest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCLI(t *testing.T) {
+	modern := filepath.Join(t.TempDir(), "gitleaks")
+	require.NoError(t, os.WriteFile(modern, []byte("#!/bin/sh\nexit 0\n"), 0o755))
+	require.Equal(t, gitleaks.CLIGit, gitleaks.DetectCLI(modern))
+
+	legacy := filepath.Join(t.TempDir(), "gitleaks")
+	require.NoError(t, os.WriteFile(legacy, []byte("#!/bin/sh\n[ \"$1\" != git ]\n"), 0o755))
+	require.Equal(t, gitleaks.CLIProtect, gitleaks.DetectCLI(legacy))
+}
+
+func TestPreCommit_CLI(t *testing.T) {
+	tests := []struct {
+		cli  gitleaks.CLI
+		call string
+	}{
+		{cli: gitleaks.CLIGit, call: "git --no-banner --staged --report-format json"},
+		{cli: gitleaks.CLIProtect, call: "protect --no-banner --staged --report-format json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.cli), func(t *testing.T) {
+			repo := setupRepo(t)
+			gitleaksPath := createReportingMockGitleaks(t, 0)
+			hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: tt.cli, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+
+			stage(t, repo, "a.txt")
+			require.NoError(t, gitleaks.PreCommit(hook))
+			require.Equal(t, []string{tt.call}, loggedCalls(t, gitleaksPath))
+		})
+	}
+}
+
+func TestPreCommit_VersionChanged(t *testing.T) {
+	repo := setupRepo(t)
+	gitleaksPath := createReportingMockGitleaks(t, 0)
+	var stderr bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: gitleaksPath, Version: "v8.18.0", CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &stderr}
+
+	stage(t, repo, "a.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	require.Empty(t, stderr.String())
+
+	// The binary was upgraded under the hook: warn and detect its commands
+	hook.Version = "v8.17.0"
+	require.NoError(t, gitleaks.PreCommit(hook))
+	require.Contains(t, stderr.String(), "Warning: gitleaks at "+gitleaksPath+" is version v8.18.0, the hook was installed with v8.17.0")
+	calls := loggedCalls(t, gitleaksPath)
+	require.Equal(t, "git --help", calls[len(calls)-2])
+	require.True(t, strings.HasPrefix(calls[len(calls)-1], "git --no-banner --staged"), calls[len(calls)-1])
+}
+
+func TestPreCommit_RepoConfig(t *testing.T) {
+	repo := setupRepo(t)
+	git(t, repo, "config", "githooks.gitleaks.attest", "notes")
+	config := filepath.Join(repo, ".gitleaks.toml")
+	require.NoError(t, os.WriteFile(config, []byte("[extend]\nuseDefault = true\n"), 0o644))
+	gitleaksPath := createReportingMockGitleaks(t, 0)
+	hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: gitleaks.CLIGit, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+
+	stage(t, repo, "a.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	require.Contains(t, loggedCalls(t, gitleaksPath)[0], "--config "+config)
+
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	require.NoError(t, gitleaks.PostCommit(hook))
+	a, err := attest.Read(repo, "HEAD")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(a.Config, "sha256:"), a.Config)
+}
+
+// loggedCalls returns the arguments of each mock gitleaks call, without the
+// temporary report path
+func loggedCalls(t *testing.T, gitleaksPath string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(gitleaksPath), "calls"))
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i, call := range calls {
+		calls[i], _, _ = strings.Cut(call, " --report-path")
+	}
+	return calls
+}
+
+func TestPreCommit_LayeredConfig(t *testing.T) {
+	repo := setupRepo(t)
+	hooksHome := t.TempDir()
+	globalDir := filepath.Join(hooksHome, "gitleaks")
+	require.NoError(t, os.MkdirAll(globalDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(globalDir, "config.toml"), []byte("[extend]\nuseDefault = true\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitleaks.toml"), []byte("[[rules]]\nid = \"x\"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitleaksignore"), []byte("a.txt:generic-api-key:1\n"), 0o644))
+	gitleaksPath := createReportingMockGitleaks(t, 0)
+	hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: gitleaks.CLIGit, HooksHome: hooksHome, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+
+	stage(t, repo, "a.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	call := loggedCalls(t, gitleaksPath)[0]
+	require.Regexp(t, `--config \S+/gitleaks-scan\S*/\.gitleaks\.toml `, call, "the repository config is merged onto the global one")
+	require.Contains(t, call, "--gitleaks-ignore-path "+filepath.Join(repo, ".gitleaksignore"))
+}
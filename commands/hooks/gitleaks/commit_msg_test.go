@@ -1,24 +1,25 @@
 package gitleaks_test
 
 import (
+	"bytes"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
 	"github.com/stretchr/testify/require"
 )
 
-// TestCommitMsgHook tests the commit-msg.sh hook for conventional commit compliance
+// TestCommitMsgHook tests the commit-msg hook for conventional commit compliance
 func TestCommitMsgHook(t *testing.T) {
 	// Setup: Create mock gitleaks binary
 	tempDir := t.TempDir()
 	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
 	createMockGitleaks(t, mockGitleaksPath)
 
-	// Create test version of commit-msg.sh with mock gitleaks path
-	scriptPath := createTestScript(t, tempDir, mockGitleaksPath)
+	// Run the built-in hook with the mock gitleaks
+	runHook := hookRunner(mockGitleaksPath)
 
 	tests := []struct {
 		name           string
@@ -238,9 +239,8 @@ Scanned-by: gitleaks v8.18.0`,
 			require.NoError(t, err, "Failed to create commit message file")
 
 			// Run the commit-msg hook
-			cmd := exec.Command(scriptPath, msgFile)
-			output, err := cmd.CombinedOutput()
-			require.NoError(t, err, "Hook script failed: %s", string(output))
+			output, err := runHook(msgFile)
+			require.NoError(t, err, "Hook failed: %s", string(output))
 
 			// Read the modified commit message
 			actualContent, err := os.ReadFile(msgFile)
@@ -264,7 +264,7 @@ func TestCommitMsgHook_IdempotentExecution(t *testing.T) {
 	tempDir := t.TempDir()
 	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
 	createMockGitleaks(t, mockGitleaksPath)
-	scriptPath := createTestScript(t, tempDir, mockGitleaksPath)
+	runHook := hookRunner(mockGitleaksPath)
 
 	msgFile := filepath.Join(tempDir, "commit_msg.txt")
 	initialMessage := "feat: add feature"
@@ -277,8 +277,7 @@ Scanned-by: gitleaks v8.18.0`
 	require.NoError(t, err)
 
 	// First run
-	cmd := exec.Command(scriptPath, msgFile)
-	output, err := cmd.CombinedOutput()
+	output, err := runHook(msgFile)
 	require.NoError(t, err, "First run failed: %s", string(output))
 
 	content, err := os.ReadFile(msgFile)
@@ -286,8 +285,7 @@ Scanned-by: gitleaks v8.18.0`
 	require.Equal(t, expectedAfterFirstRun, string(content))
 
 	// Second run - should not add another footer
-	cmd = exec.Command(scriptPath, msgFile)
-	output, err = cmd.CombinedOutput()
+	output, err = runHook(msgFile)
 	require.NoError(t, err, "Second run failed: %s", string(output))
 
 	content, err = os.ReadFile(msgFile)
@@ -306,7 +304,7 @@ func TestCommitMsgHook_NoBlankLineBetweenFooters(t *testing.T) {
 	tempDir := t.TempDir()
 	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
 	createMockGitleaks(t, mockGitleaksPath)
-	scriptPath := createTestScript(t, tempDir, mockGitleaksPath)
+	runHook := hookRunner(mockGitleaksPath)
 
 	msgFile := filepath.Join(tempDir, "commit_msg.txt")
 	inputMessage := `feat: add feature
@@ -317,8 +315,7 @@ Fixes: #123`
 	require.NoError(t, err)
 
 	// Run hook
-	cmd := exec.Command(scriptPath, msgFile)
-	_, err = cmd.CombinedOutput()
+	_, err = runHook(msgFile)
 	require.NoError(t, err)
 
 	// Read result
@@ -339,7 +336,7 @@ func TestCommitMsgHook_BlankLineSeparatorForHeaderOnly(t *testing.T) {
 	tempDir := t.TempDir()
 	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
 	createMockGitleaks(t, mockGitleaksPath)
-	scriptPath := createTestScript(t, tempDir, mockGitleaksPath)
+	runHook := hookRunner(mockGitleaksPath)
 
 	msgFile := filepath.Join(tempDir, "commit_msg.txt")
 	inputMessage := "feat: add feature"
@@ -348,8 +345,7 @@ func TestCommitMsgHook_BlankLineSeparatorForHeaderOnly(t *testing.T) {
 	require.NoError(t, err)
 
 	// Run hook
-	cmd := exec.Command(scriptPath, msgFile)
-	_, err = cmd.CombinedOutput()
+	_, err = runHook(msgFile)
 	require.NoError(t, err)
 
 	// Read result
@@ -370,7 +366,7 @@ func TestCommitMsgHook_GitleaksVersionFailure(t *testing.T) {
 	tempDir := t.TempDir()
 	mockGitleaksPath := filepath.Join(tempDir, "gitleaks-fail")
 	createFailingMockGitleaks(t, mockGitleaksPath)
-	scriptPath := createTestScript(t, tempDir, mockGitleaksPath)
+	runHook := hookRunner(mockGitleaksPath)
 
 	msgFile := filepath.Join(tempDir, "commit_msg.txt")
 	inputMessage := "feat: add feature"
@@ -379,8 +375,7 @@ func TestCommitMsgHook_GitleaksVersionFailure(t *testing.T) {
 	require.NoError(t, err)
 
 	// Run hook
-	cmd := exec.Command(scriptPath, msgFile)
-	output, err := cmd.CombinedOutput()
+	output, err := runHook(msgFile)
 	require.NoError(t, err, "Hook should not fail even if gitleaks version fails")
 
 	// Verify warning is shown
@@ -397,6 +392,52 @@ Scanned-by: gitleaks`
 	require.Equal(t, expectedOutput, actual, "Should add footer without version on failure")
 }
 
+// TestCommitMsgHook_CommentsAndScissors tests messages as git passes them
+// with the commit template and `git commit --verbose`
+func TestCommitMsgHook_CommentsAndScissors(t *testing.T) {
+	tempDir := t.TempDir()
+	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
+	createMockGitleaks(t, mockGitleaksPath)
+	runHook := hookRunner(mockGitleaksPath)
+
+	msgFile := filepath.Join(tempDir, "COMMIT_EDITMSG")
+	inputMessage := `feat: add feature
+
+Fixes: #123
+
+# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored, and an empty message aborts the commit.
+#
+# ------------------------ >8 ------------------------
+# Do not modify or remove the line above.
+diff --git a/config.yaml b/config.yaml
++Scanned-by: not a trailer
+`
+	expectedOutput := `feat: add feature
+
+Fixes: #123
+Scanned-by: gitleaks v8.18.0
+
+# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored, and an empty message aborts the commit.
+#
+# ------------------------ >8 ------------------------
+# Do not modify or remove the line above.
+diff --git a/config.yaml b/config.yaml
++Scanned-by: not a trailer
+`
+
+	err := os.WriteFile(msgFile, []byte(inputMessage), 0o644)
+	require.NoError(t, err)
+
+	output, err := runHook(msgFile)
+	require.NoError(t, err, "Hook failed: %s", string(output))
+
+	content, err := os.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, expectedOutput, string(content), "Footer should go before the comments, diff lines aren't footers")
+}
+
 // Helper functions
 
 // createMockGitleaks creates a mock gitleaks binary that returns a version
@@ -410,6 +451,40 @@ echo "v8.18.0"
 	require.NoError(t, err, "Failed to create mock gitleaks")
 }
 
+// createSecretDetectingMockGitleaks creates a mock gitleaks reporting every
+// line of the scanned COMMIT_EDITMSG with a GitHub token
+func createSecretDetectingMockGitleaks(t *testing.T, path string) {
+	t.Helper()
+
+	mockScript := `#!/bin/sh
+if [ "$1" = "version" ]; then
+    echo "v8.18.0"
+    exit 0
+fi
+# gitleaks 8.19+ takes the directory as argument: dir [flags] PATH
+while [ $# -gt 0 ]; do
+    case "$1" in
+        --source) source="$2"; shift ;;
+        --report-path) report="$2"; shift ;;
+        --report-format|--config) shift ;;
+        --*) ;;
+        *) path="$1" ;;
+    esac
+    shift
+done
+source="${source:-$path}"
+[ -n "$report" ] || exit 0
+findings=$(grep -n -o '.*ghp_[0-9a-f]*' "$source/COMMIT_EDITMSG" | while IFS=: read -r line match; do
+    secret=$(echo "$match" | grep -o 'ghp_[0-9a-f]*')
+    printf '{"RuleID":"github-pat","StartLine":%s,"Match":"%s","Secret":"%s"},' "$line" "$match" "$secret"
+done)
+echo "[${findings%,}]" > "$report"
+[ -z "$findings" ]
+`
+	err := os.WriteFile(path, []byte(mockScript), 0o755)
+	require.NoError(t, err, "Failed to create secret detecting mock gitleaks")
+}
+
 // createFailingMockGitleaks creates a mock gitleaks that fails
 func createFailingMockGitleaks(t *testing.T, path string) {
 	t.Helper()
@@ -421,22 +496,111 @@ exit 1
 	require.NoError(t, err, "Failed to create failing mock gitleaks")
 }
 
-// createTestScript creates a test version of commit-msg.sh with gitleaks path replaced
-func createTestScript(t *testing.T, tempDir, gitleaksPath string) string {
-	t.Helper()
+// hookRunner returns a function running the commit-msg hook with gitleaksPath
+// and returning its combined output
+func hookRunner(gitleaksPath string) func(msgFile string) ([]byte, error) {
+	return func(msgFile string) ([]byte, error) {
+		var output bytes.Buffer
+		h := gitleaks.Hook{GitleaksPath: gitleaksPath, RepoDir: filepath.Dir(msgFile), Stdout: &output, Stderr: &output}
+		err := gitleaks.CommitMsg(h, msgFile)
+		return output.Bytes(), err
+	}
+}
 
-	// Read the original script
-	originalScript := filepath.Join("..", "..", "..", "commands", "hooks", "gitleaks", "commit-msg.sh")
-	content, err := os.ReadFile(originalScript)
-	require.NoError(t, err, "Failed to read original commit-msg.sh")
+// TestCommitMsgHook_SecretsInMessage tests that secrets in the message reject
+// the commit, while comments and the generated Scanned-by trailer are not
+// scanned
+func TestCommitMsgHook_SecretsInMessage(t *testing.T) {
+	tempDir := t.TempDir()
+	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
+	createSecretDetectingMockGitleaks(t, mockGitleaksPath)
 
-	// Replace the template with actual path
-	modifiedContent := strings.ReplaceAll(string(content), "{{.GitleaksPath}}", gitleaksPath)
+	tests := []struct {
+		name         string
+		inputMessage string
+		leak         string
+	}{
+		{
+			name:         "token in body",
+			inputMessage: "fix: rotate credentials\n\nold token was ghp_0123456789abcdef\n",
+			leak:         "line 3: github-pat: old token was REDACTED",
+		},
+		{
+			name:         "token in trailer",
+			inputMessage: "fix: rotate credentials\n\nToken: ghp_0123456789abcdef\n",
+			leak:         "line 3: github-pat: Token: REDACTED",
+		},
+		{
+			name:         "token in comment",
+			inputMessage: "fix: rotate credentials\n# ghp_0123456789abcdef\n",
+		},
+		{
+			name:         "token below scissors",
+			inputMessage: "fix: rotate credentials\n# ------------------------ >8 ------------------------\n+ghp_0123456789abcdef\n",
+		},
+		{
+			name:         "scanned-by trailer",
+			inputMessage: "fix: rotate credentials\n\nScanned-by: ghp_0123456789abcdef\n",
+			leak:         "line 3: github-pat: Scanned-by: REDACTED",
+		},
+		{
+			name:         "generated scanned-by trailer",
+			inputMessage: "fix: rotate credentials\n\nScanned-by: gitleaks v8.18.0\n",
+		},
+	}
+
+	for _, cli := range []gitleaks.CLI{gitleaks.CLIGit, gitleaks.CLIProtect} {
+		for _, tt := range tests {
+			t.Run(string(cli)+"/"+tt.name, func(t *testing.T) {
+				msgFile := filepath.Join(tempDir, "COMMIT_EDITMSG")
+				require.NoError(t, os.WriteFile(msgFile, []byte(tt.inputMessage), 0o644))
+
+				var buf bytes.Buffer
+				h := gitleaks.Hook{GitleaksPath: mockGitleaksPath, CLI: cli, RepoDir: tempDir, Stdout: &buf, Stderr: &buf}
+				err := gitleaks.CommitMsg(h, msgFile)
+				output := buf.Bytes()
+				content, readErr := os.ReadFile(msgFile)
+				require.NoError(t, readErr)
+
+				if tt.leak == "" {
+					require.NoError(t, err, string(output))
+					return
+				}
+				require.ErrorIs(t, err, gitleaks.ErrLeaks)
+				require.Contains(t, string(output), "Gitleaks has detected potential secrets in the commit message:")
+				require.Contains(t, string(output), tt.leak)
+				require.NotContains(t, string(output), "ghp_0123456789abcdef", "secret must be redacted")
+				require.Equal(t, tt.inputMessage, string(content), "rejected message must not get the trailer")
+			})
+		}
+	}
+}
 
-	// Write test script
-	testScriptPath := filepath.Join(tempDir, "commit-msg-test.sh")
-	err = os.WriteFile(testScriptPath, []byte(modifiedContent), 0o755)
-	require.NoError(t, err, "Failed to create test script")
+// TestCommitMsgHook_TrailerConfig verifies .git-hooks.yaml adjusts the
+// footer by its trailer name
+func TestCommitMsgHook_TrailerConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	mockGitleaksPath := filepath.Join(tempDir, "gitleaks")
+	createMockGitleaks(t, mockGitleaksPath)
+	runHook := hookRunner(mockGitleaksPath)
+	msgFile := filepath.Join(tempDir, "COMMIT_EDITMSG")
+	config := filepath.Join(tempDir, ".git-hooks.yaml")
+
+	t.Log("The replace policy updates an existing footer")
+	require.NoError(t, os.WriteFile(config, []byte("trailers:\n  scanned-by:\n    policy: replace\n"), 0o644))
+	require.NoError(t, os.WriteFile(msgFile, []byte("feat: add feature\n\nScanned-by: gitleaks v8.0.0\n"), 0o644))
+	output, err := runHook(msgFile)
+	require.NoError(t, err, string(output))
+	content, err := os.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "feat: add feature\n\nScanned-by: gitleaks v8.18.0\n", string(content))
 
-	return testScriptPath
+	t.Log("A disabled footer is not added")
+	require.NoError(t, os.WriteFile(config, []byte("trailers:\n  scanned-by:\n    disable: true\n"), 0o644))
+	require.NoError(t, os.WriteFile(msgFile, []byte("feat: add feature\n"), 0o644))
+	output, err = runHook(msgFile)
+	require.NoError(t, err, string(output))
+	content, err = os.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "feat: add feature\n", string(content))
 }
@@ -0,0 +1,120 @@
+package gitleaks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/trailers"
+)
+
+const (
+	// FooterKey is the trailer recording that a commit was scanned by gitleaks
+	FooterKey = "Scanned-by"
+	// TrailerName is the name git config and .git-hooks.yaml adjust the
+	// footer by
+	TrailerName = "scanned-by"
+)
+
+// Trailer is the built-in trailer spec for the gitleaks footer. Git config
+// `githooks.trailer.scanned-by.*` and the trailers section of
+// .git-hooks.yaml adjust it or switch it off.
+func Trailer(version string) trailers.Spec {
+	return trailers.Spec{
+		Name:   TrailerName,
+		Key:    FooterKey,
+		Value:  "gitleaks {{.Vars.GitleaksVersion}}",
+		Hooks:  []string{"commit-msg"},
+		Policy: trailers.PolicyIfMissing,
+		Vars:   map[string]string{"GitleaksVersion": version},
+	}
+}
+
+// CommitMsg scans the commit message in msgFile for secrets, rejecting it
+// with ErrLeaks when it has any, and appends the `Scanned-by: gitleaks
+// <version>` trailer unless it is already there. A failing gitleaks only
+// skips the scan or drops the version, it never blocks the commit. No trailer
+// is added when scans are only attested in git notes.
+func CommitMsg(h Hook, msgFile string) error {
+	h.checkVersion()
+	if err := scanMessage(h, msgFile); err != nil {
+		return err
+	}
+
+	if mode, err := AttestMode(h.RepoDir); err != nil || mode == AttestNotes {
+		return err
+	}
+
+	version := Version(h.GitleaksPath)
+	if version == "" {
+		fmt.Fprintln(h.Stderr, "Warning: Failed to get gitleaks version, appending scan info without version")
+	}
+	specs, err := trailers.LoadSpecs(h.RepoDir, Trailer(version))
+	if err != nil {
+		return err
+	}
+	// Other trailers are added by git-hooks after the commit-msg scripts
+	specs = slices.DeleteFunc(specs, func(spec trailers.Spec) bool { return spec.Name != TrailerName })
+	return trailers.InjectFile(msgFile, "commit-msg", h.RepoDir, specs, h.Stdout, h.Stderr)
+}
+
+// generatedFooter matches the values of the Scanned-by trailers CommitMsg adds
+var generatedFooter = regexp.MustCompile(`^gitleaks(\s+v?[0-9][0-9A-Za-z.+-]*)?$`)
+
+// scanMessage runs gitleaks on the message git will commit: without comment
+// lines and the verbose diff, and without the generated Scanned-by trailer,
+// which carries the gitleaks version and is never a secret. Other values of
+// the trailer are scanned like the rest of the message.
+func scanMessage(h Hook, msgFile string) error {
+	data, err := os.ReadFile(msgFile)
+	if err != nil {
+		return fmt.Errorf("reading commit message: %w", err)
+	}
+	msg := trailers.Parse(string(data), trailers.Options{
+		CommentChar:  trailers.CommentChar(h.RepoDir, string(data)),
+		Conventional: true,
+	})
+	msg.RemoveFunc(FooterKey, generatedFooter.MatchString)
+
+	dir, err := os.MkdirTemp("", "gitleaks-commit-msg")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "COMMIT_EDITMSG"), []byte(msg.Committed()), 0o600); err != nil {
+		return err
+	}
+
+	findings, err := scanFindings(h, append(h.CLI.Dir(dir), "--redact")...)
+	if errors.Is(err, ErrLeaks) {
+		fmt.Fprintln(h.Stdout, "Gitleaks has detected potential secrets in the commit message:")
+		for _, f := range findings {
+			fmt.Fprintf(h.Stdout, "    line %d: %s: %s\n", f.StartLine, f.RuleID, f.Redacted())
+		}
+		fmt.Fprintf(h.Stdout, "Please remove them and commit again, the message is kept in %s.\n", msgFile)
+		return err
+	}
+	if err != nil {
+		fmt.Fprintf(h.Stderr, "Warning: Failed to scan commit message with gitleaks: %v\n", err)
+	}
+	return nil
+}
+
+// Version returns the first line of `gitleaks version`, or an empty string
+// when it fails
+func Version(gitleaksPath string) string {
+	if gitleaksPath == "" {
+		return ""
+	}
+	output, err := exec.Command(gitleaksPath, "version").Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(line)
+}
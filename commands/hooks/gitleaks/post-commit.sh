@@ -0,0 +1,19 @@
+#!/bin/sh
+
+# Post-commit hook to record the gitleaks scan of the new commit in
+# refs/notes/git-hooks when githooks.gitleaks.attest is notes or both. Commits
+# that bypassed pre-commit, e.g. made by git cherry-pick, are scanned instead.
+
+# Gitleaks path, version and commands (detected during installation)
+# gitleaks-version: {{.GitleaksVersion}}
+GITLEAKS_PATH="{{.GitleaksPath}}"
+GITLEAKS_VERSION="{{.GitleaksVersion}}"
+GITLEAKS_CLI="{{.GitleaksCLI}}"
+
+GIT_HOOKS="{{.BinaryPath}}"
+if [ ! -x "$GIT_HOOKS" ]; then
+    GIT_HOOKS=git-hooks
+fi
+
+exec "$GIT_HOOKS" gitleaks post-commit --gitleaks-path "$GITLEAKS_PATH" \
+    --gitleaks-version "$GITLEAKS_VERSION" --gitleaks-cli "$GITLEAKS_CLI"
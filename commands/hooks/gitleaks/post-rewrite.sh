@@ -0,0 +1,19 @@
+#!/bin/sh
+
+# Post-rewrite hook to run Gitleaks on the commits created by git rebase.
+# It can only report leaks, the commits already exist. Amended commits are
+# scanned by pre-commit or post-commit.
+
+# Gitleaks path, version and commands (detected during installation)
+# gitleaks-version: {{.GitleaksVersion}}
+GITLEAKS_PATH="{{.GitleaksPath}}"
+GITLEAKS_VERSION="{{.GitleaksVersion}}"
+GITLEAKS_CLI="{{.GitleaksCLI}}"
+
+GIT_HOOKS="{{.BinaryPath}}"
+if [ ! -x "$GIT_HOOKS" ]; then
+    GIT_HOOKS=git-hooks
+fi
+
+exec "$GIT_HOOKS" gitleaks post-rewrite --gitleaks-path "$GITLEAKS_PATH" \
+    --gitleaks-version "$GITLEAKS_VERSION" --gitleaks-cli "$GITLEAKS_CLI" "$@"
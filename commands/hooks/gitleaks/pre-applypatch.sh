@@ -0,0 +1,18 @@
+#!/bin/sh
+
+# Pre-applypatch hook to run Gitleaks on a patch applied by git am,
+# which doesn't run pre-commit.
+
+# Gitleaks path, version and commands (detected during installation)
+# gitleaks-version: {{.GitleaksVersion}}
+GITLEAKS_PATH="{{.GitleaksPath}}"
+GITLEAKS_VERSION="{{.GitleaksVersion}}"
+GITLEAKS_CLI="{{.GitleaksCLI}}"
+
+GIT_HOOKS="{{.BinaryPath}}"
+if [ ! -x "$GIT_HOOKS" ]; then
+    GIT_HOOKS=git-hooks
+fi
+
+exec "$GIT_HOOKS" gitleaks pre-applypatch --gitleaks-path "$GITLEAKS_PATH" \
+    --gitleaks-version "$GITLEAKS_VERSION" --gitleaks-cli "$GITLEAKS_CLI" "$@"
@@ -1,21 +1,19 @@
 #!/bin/sh
 
-# Pre-commit hook to run Gitleaks on staged changes
+# Pre-commit hook to run Gitleaks on staged changes.
+# The scan is run by `git-hooks gitleaks pre-commit`, which also records it
+# for the attestation note when githooks.gitleaks.attest is notes or both.
 
-# Gitleaks path (detected during installation)
+# Gitleaks path, version and commands (detected during installation)
+# gitleaks-version: {{.GitleaksVersion}}
 GITLEAKS_PATH="{{.GitleaksPath}}"
+GITLEAKS_VERSION="{{.GitleaksVersion}}"
+GITLEAKS_CLI="{{.GitleaksCLI}}"
 
-# Run Gitleaks
-$GITLEAKS_PATH protect --no-banner --staged .
-
-# Check the exit code of Gitleaks
-if [ $? -ne 0 ]; then
-    echo "Gitleaks has detected potential secrets in your changes."
-    echo "Please run:"
-    echo "\`\`\`\n    $GITLEAKS_PATH protect --no-banner --staged -v . \n\n\`\`\`"
-    echo "to see the Gitleaks output above and remove any sensitive information before committing."
-    exit 1
+GIT_HOOKS="{{.BinaryPath}}"
+if [ ! -x "$GIT_HOOKS" ]; then
+    GIT_HOOKS=git-hooks
 fi
 
-# If Gitleaks passes, allow the commit
-exit 0
\ No newline at end of file
+exec "$GIT_HOOKS" gitleaks pre-commit --gitleaks-path "$GITLEAKS_PATH" \
+    --gitleaks-version "$GITLEAKS_VERSION" --gitleaks-cli "$GITLEAKS_CLI"
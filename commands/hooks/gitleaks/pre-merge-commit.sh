@@ -0,0 +1,18 @@
+#!/bin/sh
+
+# Pre-merge-commit hook to run Gitleaks on the result of a merge.
+# Git runs it instead of pre-commit for merges that commit without stopping.
+
+# Gitleaks path, version and commands (detected during installation)
+# gitleaks-version: {{.GitleaksVersion}}
+GITLEAKS_PATH="{{.GitleaksPath}}"
+GITLEAKS_VERSION="{{.GitleaksVersion}}"
+GITLEAKS_CLI="{{.GitleaksCLI}}"
+
+GIT_HOOKS="{{.BinaryPath}}"
+if [ ! -x "$GIT_HOOKS" ]; then
+    GIT_HOOKS=git-hooks
+fi
+
+exec "$GIT_HOOKS" gitleaks pre-merge-commit --gitleaks-path "$GITLEAKS_PATH" \
+    --gitleaks-version "$GITLEAKS_VERSION" --gitleaks-cli "$GITLEAKS_CLI" "$@"
@@ -0,0 +1,19 @@
+#!/bin/sh
+
+# Pre-push hook to run Gitleaks on the commits being pushed.
+# The ranges are computed by `git-hooks gitleaks pre-push` from the refs on
+# stdin, so commits made with --no-verify or elsewhere are scanned too.
+
+# Gitleaks path, version and commands (detected during installation)
+# gitleaks-version: {{.GitleaksVersion}}
+GITLEAKS_PATH="{{.GitleaksPath}}"
+GITLEAKS_VERSION="{{.GitleaksVersion}}"
+GITLEAKS_CLI="{{.GitleaksCLI}}"
+
+GIT_HOOKS="{{.BinaryPath}}"
+if [ ! -x "$GIT_HOOKS" ]; then
+    GIT_HOOKS=git-hooks
+fi
+
+exec "$GIT_HOOKS" gitleaks pre-push --gitleaks-path "$GITLEAKS_PATH" \
+    --gitleaks-version "$GITLEAKS_VERSION" --gitleaks-cli "$GITLEAKS_CLI" "$@"
@@ -0,0 +1,301 @@
+package gitleaks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/git-hooks/internal/attest"
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/gitleaksconfig"
+	"github.com/rudderlabs/git-hooks/internal/plural"
+)
+
+// Attestation modes of githooks.gitleaks.attest
+const (
+	// AttestFooter adds the Scanned-by trailer to commit messages
+	AttestFooter = "footer"
+	// AttestNotes writes an attestation note to refs/notes/git-hooks
+	AttestNotes = "notes"
+	// AttestBoth does both
+	AttestBoth = "both"
+)
+
+// ErrLeaks is returned when gitleaks found secrets
+var ErrLeaks = errors.New("gitleaks detected potential secrets")
+
+// Hook is the environment a gitleaks hook runs in
+type Hook struct {
+	GitleaksPath string
+	// Version is the gitleaks version the hook was installed with. The hook
+	// warns when the binary has another one.
+	Version string
+	// CLI is the command set detected when the hook was installed. It is
+	// detected again when empty or the version changed.
+	CLI CLI
+	// HooksHome holds the global gitleaks config the repository's one is
+	// layered on
+	HooksHome string
+	RepoDir   string
+	Stdout    io.Writer
+	Stderr    io.Writer
+}
+
+// AttestMode returns githooks.gitleaks.attest, footer by default
+func AttestMode(repoDir string) (string, error) {
+	value, ok, err := gitconfig.Get(repoDir, gitconfig.ScopeDefault, "githooks.gitleaks.attest")
+	if err != nil || !ok {
+		return AttestFooter, err
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case AttestFooter, AttestNotes, AttestBoth:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("githooks.gitleaks.attest: unknown mode %q, expected footer, notes or both", value)
+	}
+}
+
+// PreCommit scans the staged changes. The result is kept for PostCommit, also
+// when the commit goes ahead despite findings because the script's severity
+// is warn: it tells PostCommit the commit was scanned, and becomes its
+// attestation note when those are enabled.
+func PreCommit(h Hook) error {
+	if _, err := AttestMode(h.RepoDir); err != nil {
+		return err
+	}
+	h.checkVersion()
+
+	findings, err := protect(h)
+	if err != nil && !errors.Is(err, ErrLeaks) {
+		return err
+	}
+	if errors.Is(err, ErrLeaks) {
+		reportLeaks(h.Stdout, "your changes", []string{h.command(append(h.CLI.Staged(), "-v")...)},
+			"to see the Gitleaks output above and remove any sensitive information before committing.")
+	}
+
+	tree, treeErr := attest.StagedTree(h.RepoDir)
+	if treeErr != nil {
+		return treeErr
+	}
+	pending := attest.Pending{
+		Attestation: attest.Attestation{
+			Tool:      "gitleaks",
+			Version:   Version(h.GitleaksPath),
+			Config:    gitleaksconfig.Find(h.HooksHome, h.RepoDir).Hash(),
+			Tree:      tree,
+			Findings:  findings,
+			Timestamp: time.Now(),
+		},
+		Head: attest.Resolve(h.RepoDir, "HEAD"),
+	}
+	if saveErr := attest.SavePending(h.RepoDir, pending); saveErr != nil {
+		return fmt.Errorf("saving gitleaks attestation: %w", saveErr)
+	}
+	return err
+}
+
+// protect scans the staged changes and returns the number of findings
+func protect(h Hook) (int, error) {
+	return scan(h, h.CLI.Staged()...)
+}
+
+// reportLeaks tells the user where secrets were found and the gitleaks
+// commands that show them
+func reportLeaks(w io.Writer, where string, commands []string, advice string) {
+	fmt.Fprintf(w, "Gitleaks has detected potential secrets in %s.\n", where)
+	fmt.Fprintln(w, "Please run:")
+	fmt.Fprint(w, "```\n")
+	for _, command := range commands {
+		fmt.Fprintf(w, "    %s\n", command)
+	}
+	fmt.Fprint(w, "\n```\n")
+	fmt.Fprintln(w, advice)
+}
+
+// Finding is one leak of a gitleaks JSON report
+type Finding struct {
+	RuleID      string
+	Description string
+	StartLine   int
+	Match       string
+	Secret      string
+}
+
+// Redacted returns the match with the secret hidden
+func (f Finding) Redacted() string {
+	if f.Secret == "" {
+		return f.Match
+	}
+	return strings.ReplaceAll(f.Match, f.Secret, "REDACTED")
+}
+
+// scan runs gitleaks with args, reporting to a temporary JSON file, and
+// returns the number of findings
+func scan(h Hook, args ...string) (int, error) {
+	findings, err := scanFindings(h, args...)
+	return len(findings), err
+}
+
+// scanFindings is scan returning the findings
+func scanFindings(h Hook, args ...string) ([]Finding, error) {
+	tmpDir, err := os.MkdirTemp("", "gitleaks-scan")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	report := filepath.Join(tmpDir, "report.json")
+
+	configArgs, err := gitleaksconfig.Find(h.HooksHome, h.RepoDir).Args(tmpDir)
+	if err != nil {
+		return nil, fmt.Errorf("layering gitleaks config: %w", err)
+	}
+	args = append(append(args, configArgs...), "--report-format", "json", "--report-path", report)
+	cmd := exec.Command(h.GitleaksPath, args...)
+	cmd.Dir = h.RepoDir
+	cmd.Stdout, cmd.Stderr = h.Stdout, h.Stderr
+	runErr := cmd.Run()
+
+	var findings []Finding
+	if data, err := os.ReadFile(report); err == nil && len(data) > 0 {
+		if err := json.Unmarshal(data, &findings); err != nil { //nolint:forbidigo
+			return nil, fmt.Errorf("reading gitleaks report: %w", err)
+		}
+	}
+
+	switch {
+	case len(findings) > 0:
+		return findings, fmt.Errorf("%w (%d %s)", ErrLeaks, len(findings), plural.Of(len(findings), "finding", "findings"))
+	case runErr != nil:
+		return nil, fmt.Errorf("running gitleaks: %w", runErr)
+	}
+	return nil, nil
+}
+
+// PostCommit writes the attestation PreCommit saved as note of the new
+// commit, signed when githooks.gitleaks.signAttestations is set. Problems are
+// reported but never fail the hook, the commit already exists.
+//
+// Without a matching PreCommit scan the commit bypassed pre-commit, e.g. it
+// was made by `git cherry-pick` or with --no-verify, and PostCommit scans it
+// instead. Leaks can only be reported for a fix before pushing.
+func PostCommit(h Hook) error {
+	pending, ok, err := attest.TakePending(h.RepoDir, "gitleaks")
+	if err != nil {
+		fmt.Fprintf(h.Stderr, "git-hooks: reading gitleaks attestation: %v\n", err)
+		return nil
+	}
+	if !ok {
+		return scanHead(h)
+	}
+	mode, err := AttestMode(h.RepoDir)
+	if err != nil {
+		fmt.Fprintf(h.Stderr, "git-hooks: %v\n", err)
+		return nil
+	}
+
+	tree, err := attest.Tree(h.RepoDir, "HEAD")
+	if err != nil {
+		fmt.Fprintf(h.Stderr, "git-hooks: %v\n", err)
+		return nil
+	}
+	// HEAD before this commit, also for amends, must be where the scan ran,
+	// or the pending scan is left over from an aborted commit
+	var mismatch string
+	switch {
+	case tree != pending.Tree:
+		mismatch = "the committed tree differs from the scanned one"
+	case attest.Resolve(h.RepoDir, "HEAD@{1}") != pending.Head:
+		mismatch = "it was scanned for an earlier commit"
+	}
+	if mismatch != "" {
+		if mode != AttestFooter {
+			fmt.Fprintf(h.Stderr, "git-hooks: not recording gitleaks attestation, %s\n", mismatch)
+		}
+		return scanHead(h)
+	}
+	if mode == AttestFooter {
+		return nil
+	}
+
+	if sign, err := signAttestations(h.RepoDir); err != nil {
+		fmt.Fprintf(h.Stderr, "git-hooks: %v\n", err)
+	} else if sign {
+		signer, ok, err := attest.SignerFromConfig(h.RepoDir)
+		switch {
+		case err != nil:
+			fmt.Fprintf(h.Stderr, "git-hooks: %v\n", err)
+		case !ok:
+			fmt.Fprintln(h.Stderr, "git-hooks: not signing gitleaks attestation, user.signingKey is not set")
+		default:
+			if err := signer.Sign(&pending.Attestation); err != nil {
+				fmt.Fprintf(h.Stderr, "git-hooks: %v\n", err)
+			}
+		}
+	}
+
+	if err := attest.Write(h.RepoDir, "HEAD", pending.Attestation); err != nil {
+		fmt.Fprintf(h.Stderr, "git-hooks: %v\n", err)
+	}
+	return nil
+}
+
+// scanHead scans the commit PostCommit runs for when it bypassed pre-commit.
+// Commits of a rebase are left to PostRewrite, which scans them all at once.
+func scanHead(h Hook) error {
+	if rebasing(h.RepoDir) {
+		return nil
+	}
+	commit := attest.Resolve(h.RepoDir, "HEAD")
+	if commit == "" {
+		return nil
+	}
+
+	h.checkVersion()
+	fmt.Fprintln(h.Stdout, "Scanning the new commit with gitleaks, it didn't go through pre-commit")
+	logOpts := "--no-walk " + commit
+	_, err := scan(h, h.CLI.Log(logOpts)...)
+	if errors.Is(err, ErrLeaks) {
+		reportLeaks(h.Stdout, "the new commit",
+			[]string{h.command(append(h.CLI.Log(logOpts), "-v")...)},
+			"to see the Gitleaks output. The commit already exists: amend it to remove any sensitive information before pushing.")
+	}
+	return err
+}
+
+// rebasing reports whether a rebase is in progress, git runs post-commit for
+// each commit it picks
+func rebasing(repoDir string) bool {
+	cmd := exec.Command("git", "rev-parse", "--absolute-git-dir")
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	gitDir := strings.TrimSpace(string(output))
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, dir)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func signAttestations(repoDir string) (bool, error) {
+	value, ok, err := gitconfig.Get(repoDir, gitconfig.ScopeDefault, "githooks.gitleaks.signAttestations")
+	if err != nil || !ok {
+		return false, err
+	}
+	sign, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("githooks.gitleaks.signAttestations: %w", err)
+	}
+	return sign, nil
+}
@@ -0,0 +1,215 @@
+package gitleaks_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/rudderlabs/git-hooks/internal/attest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreCommit_Notes(t *testing.T) {
+	repo := setupRepo(t)
+	git(t, repo, "config", "githooks.gitleaks.attest", "notes")
+	hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 0), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+
+	stage(t, repo, "a.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	require.NoError(t, gitleaks.PostCommit(hook))
+
+	a, err := attest.Read(repo, "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, "gitleaks", a.Tool)
+	require.Equal(t, "v8.18.0", a.Version)
+	require.Equal(t, "default", a.Config)
+	require.Zero(t, a.Findings)
+
+	res := attest.Check(repo, "HEAD", attest.Verifier{}, false)
+	require.True(t, res.OK, res.Message)
+
+	// No footer in notes mode
+	msgFile := filepath.Join(repo, ".git", "COMMIT_EDITMSG")
+	require.NoError(t, os.WriteFile(msgFile, []byte("feat: add feature\n"), 0o644))
+	require.NoError(t, gitleaks.CommitMsg(hook, msgFile))
+	content, err := os.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "feat: add feature\n", string(content))
+}
+
+func TestPreCommit_Leaks(t *testing.T) {
+	repo := setupRepo(t)
+	git(t, repo, "config", "githooks.gitleaks.attest", "both")
+	var stdout bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 2), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+
+	stage(t, repo, "a.txt")
+	err := gitleaks.PreCommit(hook)
+	require.ErrorIs(t, err, gitleaks.ErrLeaks)
+	require.Contains(t, stdout.String(), "Gitleaks has detected potential secrets")
+
+	// With severity warn the commit goes ahead, the note records the findings
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	require.NoError(t, gitleaks.PostCommit(hook))
+	res := attest.Check(repo, "HEAD", attest.Verifier{}, false)
+	require.False(t, res.OK)
+	require.Contains(t, res.Message, "2 findings")
+}
+
+func TestPostCommit_TreeMismatch(t *testing.T) {
+	repo := setupRepo(t)
+	git(t, repo, "config", "githooks.gitleaks.attest", "notes")
+	var stderr bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 0), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &stderr}
+
+	stage(t, repo, "a.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	stage(t, repo, "b.txt")
+	git(t, repo, "commit", "-q", "-m", "add files")
+	require.NoError(t, gitleaks.PostCommit(hook))
+
+	require.Contains(t, stderr.String(), "differs from the scanned one")
+	_, err := attest.Read(repo, "HEAD")
+	require.ErrorIs(t, err, attest.ErrNoAttestation)
+}
+
+func TestPostCommit_LeftoverAttestation(t *testing.T) {
+	repo := setupRepo(t)
+	git(t, repo, "config", "githooks.gitleaks.attest", "notes")
+	var stderr bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 0), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &stderr}
+
+	stage(t, repo, "README.md")
+	git(t, repo, "commit", "-q", "-m", "initial")
+
+	t.Log("An amend is attested, HEAD before it is where the scan ran")
+	stage(t, repo, "a.txt")
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	stage(t, repo, "b.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	git(t, repo, "commit", "-q", "--amend", "-m", "add files")
+	require.NoError(t, gitleaks.PostCommit(hook))
+	_, err := attest.Read(repo, "HEAD")
+	require.NoError(t, err, stderr.String())
+
+	t.Log("The attestation of an aborted commit isn't recorded for a later one of the same tree")
+	stage(t, repo, "c.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	git(t, repo, "reset", "-q", "--soft", "HEAD~1")
+	git(t, repo, "commit", "-q", "--no-verify", "-m", "add files")
+	require.NoError(t, gitleaks.PostCommit(hook))
+	require.Contains(t, stderr.String(), "it was scanned for an earlier commit")
+	_, err = attest.Read(repo, "HEAD")
+	require.ErrorIs(t, err, attest.ErrNoAttestation)
+}
+
+func TestPostCommit_ScansBypassedCommit(t *testing.T) {
+	repo := setupRepo(t)
+	gitleaksPath := createReportingMockGitleaks(t, 1)
+	var stdout bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+
+	// E.g. git cherry-pick, which runs post-commit but not pre-commit
+	stage(t, repo, "a.txt")
+	git(t, repo, "commit", "-q", "--no-verify", "-m", "add a.txt")
+	require.ErrorIs(t, gitleaks.PostCommit(hook), gitleaks.ErrLeaks)
+	head := strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+	require.Equal(t, []string{"--no-walk " + head}, loggedLogOpts(t, gitleaksPath))
+	require.Contains(t, stdout.String(), "Scanning the new commit with gitleaks, it didn't go through pre-commit")
+	require.Contains(t, stdout.String(), "Gitleaks has detected potential secrets in the new commit.")
+
+	t.Log("Commits picked by a rebase are left to post-rewrite")
+	gitleaksPath = createReportingMockGitleaks(t, 1)
+	hook.GitleaksPath = gitleaksPath
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git", "rebase-merge"), 0o755))
+	require.NoError(t, gitleaks.PostCommit(hook))
+	require.Empty(t, loggedLogOpts(t, gitleaksPath))
+}
+
+func TestPreCommit_FooterModeSkipsAttestation(t *testing.T) {
+	repo := setupRepo(t)
+	var stdout bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 0), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+
+	stage(t, repo, "a.txt")
+	require.NoError(t, gitleaks.PreCommit(hook))
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	require.NoError(t, gitleaks.PostCommit(hook))
+
+	_, err := attest.Read(repo, "HEAD")
+	require.ErrorIs(t, err, attest.ErrNoAttestation)
+	// The commit went through pre-commit, post-commit doesn't scan it again
+	require.Empty(t, stdout.String())
+}
+
+// createReportingMockGitleaks creates a mock gitleaks whose scans report the
+// given number of findings. Its arguments are logged to a calls file next to
+// it.
+func createReportingMockGitleaks(t *testing.T, findings int) string {
+	t.Helper()
+
+	leaks := make([]string, findings)
+	for i := range leaks {
+		leaks[i] = `{"RuleID":"generic-api-key"}`
+	}
+	exitCode := "0"
+	if findings > 0 {
+		exitCode = "1"
+	}
+	path := filepath.Join(t.TempDir(), "gitleaks")
+	mockScript := `#!/bin/sh
+if [ "$1" = "version" ]; then
+    echo "v8.18.0"
+    exit 0
+fi
+echo "$@" >> "$(dirname "$0")/calls"
+while [ $# -gt 0 ]; do
+    if [ "$1" = "--report-path" ]; then
+        echo '[` + strings.Join(leaks, ",") + `]' > "$2"
+    fi
+    shift
+done
+exit ` + exitCode + `
+`
+	require.NoError(t, os.WriteFile(path, []byte(mockScript), 0o755), "Failed to create mock gitleaks")
+	return path
+}
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GITLEAKS_CONFIG", "")
+
+	repo := filepath.Join(home, "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	git(t, repo, "init", "-q", "-b", "main")
+	git(t, repo, "config", "user.name", "Test User")
+	git(t, repo, "config", "user.email", "test@example.com")
+	return repo
+}
+
+func stage(t *testing.T, repo, file string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, file), []byte(file+"\n"), 0o644))
+	git(t, repo, "add", file)
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+	return string(output)
+}
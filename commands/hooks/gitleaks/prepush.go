@@ -0,0 +1,77 @@
+package gitleaks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/plural"
+	"github.com/rudderlabs/git-hooks/internal/prepush"
+)
+
+// PrePush scans the commits a push adds to remote, read from the pre-push
+// ref updates on stdin. It catches commits that never went through the
+// pre-commit scan: made with --no-verify, applied with `git am` or created on
+// another machine.
+func PrePush(h Hook, remote string, stdin io.Reader) error {
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("reading pre-push input: %w", err)
+	}
+
+	h.checkVersion()
+	var leaked []string
+	for _, u := range prepush.Parse(input) {
+		if u.Deleted() {
+			continue
+		}
+		revisions := u.Revisions(h.RepoDir, remote)
+		count, err := countCommits(h.RepoDir, revisions)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+
+		logOpts := strings.Join(revisions, " ")
+		fmt.Fprintf(h.Stdout, "Scanning %d %s pushed to %s with gitleaks\n", count, plural.Of(count, "commit", "commits"), u.RemoteRef)
+		_, err = scan(h, h.CLI.Log(logOpts)...)
+		if errors.Is(err, ErrLeaks) {
+			leaked = append(leaked, logOpts)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(leaked) == 0 {
+		return nil
+	}
+	commands := make([]string, len(leaked))
+	for i, logOpts := range leaked {
+		commands[i] = h.command(append(h.CLI.Log(logOpts), "-v")...)
+	}
+	reportLeaks(h.Stdout, "the commits being pushed", commands,
+		"to see the Gitleaks output and rewrite the commits to remove any sensitive information before pushing.")
+	return ErrLeaks
+}
+
+// countCommits returns the number of commits selected by revisions
+func countCommits(repoDir string, revisions []string) (int, error) {
+	args := append([]string{"rev-list", "--count"}, revisions...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("counting pushed commits: %w", err)
+	}
+	var count int
+	if _, err := fmt.Sscan(string(output), &count); err != nil {
+		return 0, fmt.Errorf("counting pushed commits: %w", err)
+	}
+	return count, nil
+}
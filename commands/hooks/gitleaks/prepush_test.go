@@ -0,0 +1,99 @@
+package gitleaks_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrePush(t *testing.T) {
+	repo := setupRepo(t)
+	remote := filepath.Join(filepath.Dir(repo), "remote.git")
+	git(t, repo, "init", "-q", "--bare", remote)
+	git(t, repo, "remote", "add", "origin", remote)
+
+	stage(t, repo, "a.txt")
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	base := strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+	git(t, repo, "push", "-q", "origin", "main")
+
+	stage(t, repo, "b.txt")
+	git(t, repo, "commit", "-q", "-m", "add b.txt")
+	head := strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+
+	zero := strings.Repeat("0", 40)
+	tests := []struct {
+		name    string
+		stdin   string
+		logOpts []string
+	}{
+		{
+			name:    "existing branch",
+			stdin:   "refs/heads/main " + head + " refs/heads/main " + base + "\n",
+			logOpts: []string{head + " --not " + base},
+		},
+		{
+			name:    "new branch",
+			stdin:   "refs/heads/feature " + head + " refs/heads/feature " + zero + "\n",
+			logOpts: []string{head + " --not --remotes=origin/*"},
+		},
+		{
+			name:  "deleted branch",
+			stdin: "(delete) " + zero + " refs/heads/old " + base + "\n",
+		},
+		{
+			name:  "nothing new",
+			stdin: "refs/heads/tag " + base + " refs/heads/tag " + zero + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitleaksPath := createReportingMockGitleaks(t, 0)
+			hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+
+			require.NoError(t, gitleaks.PrePush(hook, "origin", strings.NewReader(tt.stdin)))
+			require.Equal(t, tt.logOpts, loggedLogOpts(t, gitleaksPath))
+		})
+	}
+}
+
+func TestPrePush_Leaks(t *testing.T) {
+	repo := setupRepo(t)
+	stage(t, repo, "a.txt")
+	git(t, repo, "commit", "-q", "-m", "add a.txt")
+	head := strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+
+	var stdout bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 1), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+	stdin := "refs/heads/main " + head + " refs/heads/main " + strings.Repeat("0", 40) + "\n"
+
+	err := gitleaks.PrePush(hook, "origin", strings.NewReader(stdin))
+	require.ErrorIs(t, err, gitleaks.ErrLeaks)
+	require.Contains(t, stdout.String(), "Gitleaks has detected potential secrets in the commits being pushed.")
+	require.Contains(t, stdout.String(), `--log-opts "`+head+` --not --remotes"`)
+}
+
+// loggedLogOpts returns the --log-opts of each gitleaks call
+func loggedLogOpts(t *testing.T, gitleaksPath string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(gitleaksPath), "calls"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var logOpts []string
+	for _, call := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		require.True(t, strings.HasPrefix(call, "detect --no-banner --log-opts "), call)
+		opts, _, _ := strings.Cut(strings.TrimPrefix(call, "detect --no-banner --log-opts "), " --report-format")
+		logOpts = append(logOpts, opts)
+	}
+	return logOpts
+}
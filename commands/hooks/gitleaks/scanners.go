@@ -0,0 +1,75 @@
+package gitleaks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/plural"
+)
+
+// PreMergeCommit scans the result of a merge before the merge commit is
+// created. Git runs pre-merge-commit instead of pre-commit for merges that
+// don't stop, and pre-commit with the conflict resolutions when they do.
+func PreMergeCommit(h Hook) error {
+	return scanStaged(h, "the merge result", "committing the merge")
+}
+
+// PreApplyPatch scans a patch `git am` applied to the index, before it is
+// committed
+func PreApplyPatch(h Hook) error {
+	return scanStaged(h, "the patch applied by git am", "committing it (git am --abort, or fix and git am --continue)")
+}
+
+func scanStaged(h Hook, what, before string) error {
+	h.checkVersion()
+	fmt.Fprintf(h.Stdout, "Scanning %s with gitleaks\n", what)
+	_, err := protect(h)
+	if errors.Is(err, ErrLeaks) {
+		reportLeaks(h.Stdout, what, []string{h.command(append(h.CLI.Staged(), "-v")...)},
+			"to see the Gitleaks output above and remove any sensitive information before "+before+".")
+	}
+	return err
+}
+
+// PostRewrite scans the commits `git rebase` created, read as
+// `<old-sha> <new-sha>` lines from stdin. Git doesn't run pre-commit for
+// rebased commits, and the commits already exist when it runs, so leaks can
+// only be reported for a fix before pushing. An amend (the command) went
+// through pre-commit or post-commit already and is skipped.
+func PostRewrite(h Hook, command string, stdin io.Reader) error {
+	if command == "amend" {
+		return nil
+	}
+
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("reading post-rewrite input: %w", err)
+	}
+
+	var commits []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(input), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || seen[fields[1]] {
+			continue
+		}
+		seen[fields[1]] = true
+		commits = append(commits, fields[1])
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+
+	h.checkVersion()
+	fmt.Fprintf(h.Stdout, "Scanning %d %s rewritten by %s with gitleaks\n", len(commits), plural.Of(len(commits), "commit", "commits"), command)
+	logOpts := "--no-walk " + strings.Join(commits, " ")
+	_, err = scan(h, h.CLI.Log(logOpts)...)
+	if errors.Is(err, ErrLeaks) {
+		reportLeaks(h.Stdout, "the commits rewritten by "+command,
+			[]string{h.command(append(h.CLI.Log(logOpts), "-v")...)},
+			"to see the Gitleaks output. The commits already exist: amend or rebase them to remove any sensitive information before pushing.")
+	}
+	return err
+}
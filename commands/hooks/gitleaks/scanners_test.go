@@ -0,0 +1,71 @@
+package gitleaks_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStagedScanners(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(gitleaks.Hook) error
+		what   string
+		advice string
+	}{
+		{name: "pre-merge-commit", run: gitleaks.PreMergeCommit, what: "the merge result", advice: "before committing the merge"},
+		{name: "pre-applypatch", run: gitleaks.PreApplyPatch, what: "the patch applied by git am", advice: "git am --abort"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := setupRepo(t)
+			stage(t, repo, "a.txt")
+
+			var stdout bytes.Buffer
+			hook := gitleaks.Hook{GitleaksPath: createReportingMockGitleaks(t, 0), CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+			require.NoError(t, tt.run(hook))
+			require.Equal(t, "Scanning "+tt.what+" with gitleaks\n", stdout.String())
+
+			stdout.Reset()
+			hook.GitleaksPath = createReportingMockGitleaks(t, 1)
+			require.ErrorIs(t, tt.run(hook), gitleaks.ErrLeaks)
+			require.Contains(t, stdout.String(), "Gitleaks has detected potential secrets in "+tt.what+".")
+			require.Contains(t, stdout.String(), tt.advice)
+		})
+	}
+}
+
+func TestPostRewrite(t *testing.T) {
+	repo := setupRepo(t)
+	gitleaksPath := createReportingMockGitleaks(t, 0)
+	var stdout bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+
+	// Squashed commits map several old commits to the same new one
+	stdin := "aaa 111\nbbb 222\nccc 222 extra\n"
+	require.NoError(t, gitleaks.PostRewrite(hook, "rebase", strings.NewReader(stdin)))
+	require.Equal(t, "Scanning 2 commits rewritten by rebase with gitleaks\n", stdout.String())
+	require.Equal(t, []string{"--no-walk 111 222"}, loggedLogOpts(t, gitleaksPath))
+
+	stdout.Reset()
+	hook.GitleaksPath = createReportingMockGitleaks(t, 1)
+	require.ErrorIs(t, gitleaks.PostRewrite(hook, "rebase", strings.NewReader("aaa 111\n")), gitleaks.ErrLeaks)
+	require.Contains(t, stdout.String(), "Gitleaks has detected potential secrets in the commits rewritten by rebase.")
+	require.Contains(t, stdout.String(), `--log-opts "--no-walk 111"`)
+}
+
+func TestPostRewrite_Amend(t *testing.T) {
+	repo := setupRepo(t)
+	gitleaksPath := createReportingMockGitleaks(t, 1)
+	var stdout bytes.Buffer
+	hook := gitleaks.Hook{GitleaksPath: gitleaksPath, CLI: gitleaks.CLIProtect, RepoDir: repo, Stdout: &stdout, Stderr: &bytes.Buffer{}}
+
+	// pre-commit or post-commit already scanned the amended commit
+	require.NoError(t, gitleaks.PostRewrite(hook, "amend", strings.NewReader("aaa 111\n")))
+	require.Empty(t, stdout.String())
+	require.Empty(t, loggedLogOpts(t, gitleaksPath))
+}
@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/plural"
+	"github.com/rudderlabs/git-hooks/internal/shim"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+var Implode = &cli.Command{
+	Name:      "implode",
+	Usage:     "Revert git-hooks configuration",
+	ArgsUsage: "[REPO]",
+	Flags: append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:    "yes",
+			Aliases: []string{"y"},
+			Usage:   "Do not ask for confirmation",
+		},
+		&cli.BoolFlag{
+			Name:  "keep-scripts",
+			Usage: "Only remove the hook shims and the core.hooksPath configuration, keep user scripts",
+		},
+	}, installFlags()...),
+	Action: func(c *cli.Context) error {
+		inst, err := resolveInstallation(c)
+		if err != nil {
+			return err
+		}
+		return implodeGitHooks(inst, c.Bool("yes"), c.Bool("keep-scripts"))
+	},
+}
+
+func implodeGitHooks(inst installation, yes, keepScripts bool) error {
+	hooksDir := inst.dir
+
+	// Only deactivate the given directories, keep everything else
+	if len(inst.scopeDirs) > 0 {
+		if err := removeScopes(inst.scopeDirs); err != nil {
+			return err
+		}
+		fmt.Printf("git-hooks deactivated for: %s\n", strings.Join(inst.scopeDirs, ", "))
+		return nil
+	}
+
+	var scopes []string
+	if inst.scope == gitconfig.ScopeGlobal {
+		var err error
+		if scopes, err = scopedDirs(hooksDir); err != nil {
+			return fmt.Errorf("reading git-hooks scopes: %w", err)
+		}
+	}
+
+	hooksPath, configured, err := gitconfig.Get(inst.configDir, inst.scope, "core.hooksPath")
+	if err != nil {
+		return fmt.Errorf("reading Git hooks configuration: %w", err)
+	}
+	// Leave a hooksPath that was not set by git-hooks alone
+	configured = configured && filepath.Clean(hooksPath) == filepath.Clean(hooksDir)
+
+	// Local installations only own their config, the shims are shared
+	_, err = os.Stat(hooksDir)
+	dirExists := err == nil && inst.ownsDir()
+
+	if !configured && !dirExists && len(scopes) == 0 {
+		fmt.Println("git-hooks is not configured. Nothing to do.")
+		return nil
+	}
+
+	// Count and collect names of files not created by the tool
+	var userFiles []string
+	if dirExists {
+		err := filepath.Walk(hooksDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			generated := filepath.Dir(path) == hooksDir && (contains(shim.Hooks, info.Name()) || info.Name() == scopeFileName)
+			if !info.IsDir() && !generated {
+				userFiles = append(userFiles, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("analyzing git-hooks directory: %w", err)
+		}
+	}
+
+	// Ask for user confirmation
+	if len(userFiles) > 0 && !keepScripts {
+		fmt.Printf("This will delete %d file(s) in %s:\n", len(userFiles), hooksDir)
+		for _, file := range userFiles {
+			rel, _ := filepath.Rel(hooksDir, file)
+			fmt.Printf("  - %s\n", rel)
+		}
+		fmt.Println("A backup archive is created before anything is deleted.")
+
+		ok, err := confirm("Are you sure you want to proceed? (y/N): ", yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("operation cancelled, nothing was changed")
+		}
+	} else if len(userFiles) == 0 && dirExists {
+		fmt.Println("No additional files found in the git-hooks directory.")
+	}
+
+	if dirExists {
+		if keepScripts {
+			removed, err := removeShims(hooksDir)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d hook %s from: %s\n", removed, plural.Of(removed, "shim", "shims"), hooksDir)
+		} else {
+			archivePath := filepath.Join(filepath.Dir(hooksDir), fmt.Sprintf(".%s-backup-%s.tar.gz",
+				strings.TrimPrefix(filepath.Base(hooksDir), "."), time.Now().Format("20060102-150405")))
+			if err := archiveDir(hooksDir, archivePath); err != nil {
+				return fmt.Errorf("archiving git-hooks directory: %w", err)
+			}
+			fmt.Printf("Backed up %s to: %s\n", hooksDir, archivePath)
+
+			// Remove the git-hooks directory
+			if err := os.RemoveAll(hooksDir); err != nil {
+				return fmt.Errorf("removing git-hooks directory: %w", err)
+			}
+			fmt.Printf("Removed directory: %s\n", hooksDir)
+		}
+	}
+
+	if configured {
+		if err := restoreHooksPath(inst); err != nil {
+			return err
+		}
+	}
+
+	if len(scopes) > 0 {
+		if err := removeScopes(scopes); err != nil {
+			return err
+		}
+		fmt.Printf("Removed git-hooks scopes: %s\n", strings.Join(scopes, ", "))
+	}
+
+	fmt.Println("Git hooks configuration has been reverted.")
+	return nil
+}
+
+// restoreHooksPath puts back the core.hooksPath saved by config, or unsets it
+// if there was none
+func restoreHooksPath(inst installation) error {
+	previous, ok, err := gitconfig.Get(inst.configDir, inst.scope, previousHooksPathKey)
+	if err != nil {
+		return fmt.Errorf("reading previous Git hooks configuration: %w", err)
+	}
+
+	if !ok {
+		if err := gitconfig.Unset(inst.configDir, inst.scope, "core.hooksPath"); err != nil {
+			return fmt.Errorf("unsetting Git hooks configuration: %w", err)
+		}
+		fmt.Println("Reset Git's core.hooksPath configuration")
+		return nil
+	}
+
+	if err := gitconfig.Set(inst.configDir, inst.scope, "core.hooksPath", previous); err != nil {
+		return fmt.Errorf("restoring Git hooks configuration: %w", err)
+	}
+	if err := gitconfig.Unset(inst.configDir, inst.scope, previousHooksPathKey); err != nil {
+		return fmt.Errorf("removing saved Git hooks configuration: %w", err)
+	}
+	fmt.Printf("Restored Git's core.hooksPath to: %s\n", previous)
+	return nil
+}
+
+// removeShims deletes the git-hooks shims in dir, leaving any other file
+func removeShims(dir string) (int, error) {
+	removed := 0
+	for _, hook := range shim.Hooks {
+		path := filepath.Join(dir, hook)
+		if _, err := shim.Read(path); err != nil {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, fmt.Errorf("removing shim for %s: %w", hook, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// archiveDir writes a gzipped tarball of dir to dest
+func archiveDir(dir, dest string) (err error) {
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Dir(dir), path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// confirm asks a yes/no question on stdin. It refuses to guess when stdin is
+// not a terminal, unless the caller already passed --yes.
+func confirm(prompt string, yes bool) (bool, error) {
+	if yes {
+		return true, nil
+	}
+
+	// /dev/null is a character device too, only a terminal can answer
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, fmt.Errorf("confirmation required but stdin is not a terminal, re-run with --yes")
+	}
+
+	fmt.Print(prompt)
+	response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("reading user input: %w", err)
+	}
+
+	response = strings.TrimSpace(response)
+	return response == "y" || response == "Y", nil
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImplode(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup prepares the home and returns the implode arguments
+		setup func(t *testing.T, home, hooksDir string) []string
+		// output is printed by a successful implode, err fails it instead
+		output string
+		err    string
+		check  func(t *testing.T, home, hooksDir string)
+	}{
+		{
+			name: "nothing configured",
+			setup: func(t *testing.T, home, hooksDir string) []string {
+				return []string{"implode", "--home", hooksDir}
+			},
+			output: "git-hooks is not configured. Nothing to do.",
+		},
+		{
+			name: "archives user scripts",
+			setup: func(t *testing.T, home, hooksDir string) []string {
+				configure(t, hooksDir)
+				writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "lint"), "make lint")
+				return []string{"--home", hooksDir, "implode", "--yes"}
+			},
+			output: "Reset Git's core.hooksPath configuration",
+			check: func(t *testing.T, home, hooksDir string) {
+				require.NoDirExists(t, hooksDir)
+				require.Empty(t, gitConfig(t, "--global", "core.hooksPath"))
+
+				archives, err := filepath.Glob(filepath.Join(home, ".git-hooks-backup-*.tar.gz"))
+				require.NoError(t, err)
+				require.Len(t, archives, 1)
+				files := archived(t, archives[0])
+				require.Equal(t, "#!/bin/sh\nmake lint\n", files[".git-hooks/pre-commit.d/lint"])
+				require.Contains(t, files, ".git-hooks/pre-commit")
+			},
+		},
+		{
+			name: "restores the previous hooksPath",
+			setup: func(t *testing.T, home, hooksDir string) []string {
+				git(t, home, "config", "--global", "core.hooksPath", "/opt/hooks")
+				configure(t, hooksDir)
+				require.Equal(t, []string{"/opt/hooks"}, gitConfig(t, "--global", previousHooksPathKey))
+				return []string{"implode", "--yes", "--home", hooksDir}
+			},
+			output: "Restored Git's core.hooksPath to: /opt/hooks",
+			check: func(t *testing.T, home, hooksDir string) {
+				require.Equal(t, []string{"/opt/hooks"}, gitConfig(t, "--global", "core.hooksPath"))
+				require.Empty(t, gitConfig(t, "--global", previousHooksPathKey))
+			},
+		},
+		{
+			name: "keeps scripts",
+			setup: func(t *testing.T, home, hooksDir string) []string {
+				configure(t, hooksDir)
+				writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "lint"), "make lint")
+				writeScript(t, filepath.Join(hooksDir, "pre-push"), "make test")
+				require.NoError(t, os.WriteFile(filepath.Join(hooksDir, "notes.txt"), []byte("mine\n"), 0o644))
+				return []string{"implode", "--keep-scripts", "--home", hooksDir}
+			},
+			output: "Removed 27 hook shims from: ",
+			check: func(t *testing.T, home, hooksDir string) {
+				require.NoFileExists(t, filepath.Join(hooksDir, "pre-commit"))
+				require.FileExists(t, filepath.Join(hooksDir, "pre-commit.d", "lint"))
+				require.FileExists(t, filepath.Join(hooksDir, "pre-push"), "not a shim")
+				require.FileExists(t, filepath.Join(hooksDir, "notes.txt"))
+				require.Empty(t, gitConfig(t, "--global", "core.hooksPath"))
+			},
+		},
+		{
+			name: "requires --yes without a terminal",
+			setup: func(t *testing.T, home, hooksDir string) []string {
+				configure(t, hooksDir)
+				writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "lint"), "make lint")
+				return []string{"implode", "--home", hooksDir}
+			},
+			err: "confirmation required but stdin is not a terminal, re-run with --yes",
+			check: func(t *testing.T, home, hooksDir string) {
+				require.FileExists(t, filepath.Join(hooksDir, "pre-commit.d", "lint"))
+				require.Equal(t, []string{hooksDir}, gitConfig(t, "--global", "core.hooksPath"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := setupHome(t)
+			// Tests don't run in a terminal, like CI
+			stdin, err := os.Open(os.DevNull)
+			require.NoError(t, err)
+			t.Cleanup(func() { stdin.Close() })
+			replaceStdin(t, stdin)
+
+			hooksDir := filepath.Join(home, ".git-hooks")
+			args := tt.setup(t, home, hooksDir)
+
+			output, err := run(t, args...)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+			} else {
+				require.NoError(t, err, output)
+				require.Contains(t, output, tt.output)
+			}
+			if tt.check != nil {
+				tt.check(t, home, hooksDir)
+			}
+		})
+	}
+}
+
+// configure installs git-hooks globally into hooksDir
+func configure(t *testing.T, hooksDir string) {
+	t.Helper()
+
+	output, err := run(t, "config", "--home", hooksDir)
+	require.NoError(t, err, output)
+}
+
+// replaceStdin makes f the process stdin for the test
+func replaceStdin(t *testing.T, f *os.File) {
+	t.Helper()
+
+	stdin := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() { os.Stdin = stdin })
+}
+
+// archived returns the regular files in a tar.gz by name
+func archived(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeReg {
+			data, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[header.Name] = string(data)
+		}
+	}
+}
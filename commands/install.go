@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/buildinfo"
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/shim"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultSystemDir is where `config --system` installs shims shared by all users
+const defaultSystemDir = "/usr/local/share/git-hooks"
+
+// installation describes where the shims live and which git config scope
+// points core.hooksPath at them
+type installation struct {
+	dir   string
+	scope gitconfig.Scope
+	// configDir is the directory git config commands run in
+	configDir string
+	// scopeDirs limits a global installation to repositories below these
+	// directories via includeIf
+	scopeDirs []string
+}
+
+// ownsDir reports whether the shim directory belongs to this installation
+// alone. Local installations share the shims in the hooks home.
+func (i installation) ownsDir() bool {
+	return i.scope != gitconfig.ScopeLocal
+}
+
+// installFlags returns the flags selecting an installation mode
+func installFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "system",
+			Usage: "Install for all users via the system git config (requires root)",
+		},
+		&cli.StringFlag{
+			Name:  "system-dir",
+			Value: defaultSystemDir,
+			Usage: "Directory for system-wide shims, used with --system",
+		},
+		&cli.BoolFlag{
+			Name:  "local",
+			Usage: "Only set core.hooksPath for the repository given as argument (default: current directory)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "scope-dir",
+			Usage: "Only activate git-hooks for repositories below this directory, can be repeated",
+		},
+		HomeFlag,
+	}
+}
+
+// resolveInstallation returns the installation selected by the command flags
+func resolveInstallation(c *cli.Context) (installation, error) {
+	modes := 0
+	for _, flag := range []string{"system", "local", "scope-dir"} {
+		if c.IsSet(flag) {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return installation{}, fmt.Errorf("--system, --local and --scope-dir are mutually exclusive")
+	}
+
+	if c.Bool("system") {
+		return installation{dir: c.String("system-dir"), scope: gitconfig.ScopeSystem, configDir: "."}, nil
+	}
+
+	hooksDir, err := hooksHome(c)
+	if err != nil {
+		return installation{}, err
+	}
+
+	if c.Bool("local") {
+		repo := "."
+		if c.NArg() > 0 {
+			repo = c.Args().First()
+		}
+		repo, err := repositoryRoot(repo)
+		if err != nil {
+			return installation{}, err
+		}
+		return installation{dir: hooksDir, scope: gitconfig.ScopeLocal, configDir: repo}, nil
+	}
+
+	var scopeDirs []string
+	for _, dir := range c.StringSlice("scope-dir") {
+		dir, err := normalizeScopeDir(dir)
+		if err != nil {
+			return installation{}, fmt.Errorf("resolving scope directory: %w", err)
+		}
+		scopeDirs = append(scopeDirs, dir)
+	}
+
+	return installation{dir: hooksDir, scope: gitconfig.ScopeGlobal, configDir: ".", scopeDirs: scopeDirs}, nil
+}
+
+// repositoryRoot returns the top-level directory of the repository at path
+func repositoryRoot(path string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", path, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// params returns the shim params for the running binary
+func (i installation) params() (shim.Params, error) {
+	params, err := shim.Current(buildinfo.Version())
+	if err != nil {
+		return params, err
+	}
+
+	// System-wide shims must not pin a home directory, every user resolves
+	// their own
+	if i.scope == gitconfig.ScopeSystem {
+		params.SystemDir = i.dir
+	} else {
+		params.Home = i.dir
+	}
+	return params, nil
+}
+
+func (i installation) String() string {
+	if i.scope == gitconfig.ScopeLocal {
+		return fmt.Sprintf("%s (local to %s)", i.dir, i.configDir)
+	}
+	if len(i.scopeDirs) > 0 {
+		return fmt.Sprintf("%s (for repositories in %s)", i.dir, strings.Join(i.scopeDirs, ", "))
+	}
+	return fmt.Sprintf("%s (%s)", i.dir, i.scope)
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_System(t *testing.T) {
+	home := setupHome(t)
+	systemConfig := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "0")
+	t.Setenv("GIT_CONFIG_SYSTEM", systemConfig)
+	systemDir := filepath.Join(t.TempDir(), "git-hooks")
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	output, err := run(t, "config", "--system", "--system-dir", systemDir)
+	require.NoError(t, err, output)
+	require.Equal(t, []string{systemDir}, gitConfig(t, "--system", "core.hooksPath"))
+	require.Empty(t, gitConfig(t, "--global", "core.hooksPath"))
+	require.NoDirExists(t, hooksDir, "the user's home is left alone")
+
+	t.Log("System shims point at the system scripts and leave the home to each user")
+	content, err := os.ReadFile(filepath.Join(systemDir, "pre-commit"))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "GIT_HOOKS_SYSTEM_DIR='"+systemDir+"'\n")
+	require.NotContains(t, string(content), "GIT_HOOKS_HOME")
+
+	t.Log("System scripts run before the user's own")
+	log := filepath.Join(home, "hooks.log")
+	writeScript(t, filepath.Join(systemDir, "pre-commit.d", "policy"), "echo system >> "+log)
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "lint"), "echo global >> "+log)
+	repo := filepath.Join(home, "repo")
+	git(t, home, "init", "-q", repo)
+
+	cmd := exec.Command(filepath.Join(systemDir, "pre-commit"))
+	cmd.Dir = repo
+	hookOutput, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(hookOutput))
+	ran, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Equal(t, []string{"system", "global"}, strings.Fields(string(ran)))
+
+	t.Log("Imploding the system installation keeps the user's")
+	output, err = run(t, "implode", "--system", "--system-dir", systemDir, "--yes")
+	require.NoError(t, err, output)
+	require.NoDirExists(t, systemDir)
+	require.Empty(t, gitConfig(t, "--system", "core.hooksPath"))
+	require.FileExists(t, filepath.Join(hooksDir, "pre-commit.d", "lint"))
+}
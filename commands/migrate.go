@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/rudderlabs/git-hooks/internal/migrate"
+	"github.com/rudderlabs/git-hooks/internal/plural"
+	"github.com/urfave/cli/v2"
+)
+
+var Migrate = &cli.Command{
+	Name:  "migrate",
+	Usage: "Convert hooks managed by other tools to git-hooks",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "husky",
+			Usage:     "Move Husky hooks into .git-hooks and stop Husky from overriding core.hooksPath",
+			ArgsUsage: "[REPO]",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only print the changes",
+				},
+				&cli.BoolFlag{
+					Name:    "yes",
+					Aliases: []string{"y"},
+					Usage:   "Do not ask for confirmation",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				repo := "."
+				if c.NArg() > 0 {
+					repo = c.Args().First()
+				}
+				return migrateHusky(repo, c.Bool("dry-run"), c.Bool("yes"))
+			},
+		},
+	},
+}
+
+func migrateHusky(repo string, dryRun, yes bool) error {
+	plan, err := migrate.PlanHusky(repo)
+	if errors.Is(err, migrate.ErrNoHusky) {
+		fmt.Printf("No Husky hooks found in %s. Nothing to do.\n", plan.Repo)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if plan.Empty() {
+		fmt.Printf("%s has no Husky hooks left to migrate. Nothing to do.\n", plan.Repo)
+		return nil
+	}
+
+	plan.WriteSummary(os.Stdout)
+	if dryRun {
+		fmt.Println("\nDry run, no changes made.")
+		return nil
+	}
+
+	// Husky re-sets core.hooksPath on every install while the prepare
+	// script is there, but it's the user's package.json
+	removePrepare := false
+	if plan.Prepare != "" {
+		if !yes {
+			fmt.Println()
+		}
+		if removePrepare, err = confirm("Remove the Husky prepare script from package.json? (y/N): ", yes); err != nil {
+			return err
+		}
+		if !removePrepare {
+			fmt.Println("Keeping the prepare script, `npm install` will set core.hooksPath again.")
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := plan.Apply(ctx, removePrepare); err != nil {
+		return fmt.Errorf("migrating Husky hooks: %w", err)
+	}
+	fmt.Printf("\n✅ Migrated %d Husky %s to .git-hooks\n", len(plan.Moves), plural.Of(len(plan.Moves), "hook", "hooks"))
+	return nil
+}
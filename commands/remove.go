@@ -14,27 +14,36 @@ var Remove = &cli.Command{
 	Subcommands: []*cli.Command{
 		{
 			Name:  "gitleaks",
-			Usage: "remove the gitleaks pre-commit hook",
+			Usage: "remove the gitleaks hooks",
+			Flags: []cli.Flag{HomeFlag},
 			Action: func(c *cli.Context) error {
-				return removeGitLeaks()
+				hooksDir, err := hooksHome(c)
+				if err != nil {
+					return err
+				}
+				return removeGitLeaks(hooksDir)
 			},
 		},
 	},
 }
 
-func removeGitLeaks() error {
-	hooksDir := filepath.Join(os.Getenv("HOME"), ".git-hooks", "pre-commit.d")
-	scriptPath := filepath.Join(hooksDir, "gitleaks")
-
-	err := os.Remove(scriptPath)
-	if err != nil {
+func removeGitLeaks(hooksDir string) error {
+	removed := 0
+	for _, script := range gitleaksScripts {
+		scriptPath := filepath.Join(hooksDir, script.hook+".d", "gitleaks")
+		err := os.Remove(scriptPath)
 		if os.IsNotExist(err) {
-			fmt.Println("Gitleaks pre-commit hook not found. Nothing to remove.")
-			return nil
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("removing gitleaks %s script: %w", script.hook, err)
 		}
-		return fmt.Errorf("removing gitleaks script: %w", err)
+		fmt.Printf("Gitleaks %s hook removed from: %s\n", script.hook, scriptPath)
+		removed++
 	}
 
-	fmt.Printf("Gitleaks pre-commit hook removed from: %s\n", scriptPath)
+	if removed == 0 {
+		fmt.Println("Gitleaks hooks not found. Nothing to remove.")
+	}
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	cleangit "github.com/rudderlabs/git-hooks/internal/clean-local-git"
+	"github.com/rudderlabs/git-hooks/internal/plural"
 	"github.com/urfave/cli/v2"
 )
 
@@ -30,6 +31,7 @@ var ScanLocal = &cli.Command{
 			Name:  "auto-fix",
 			Usage: "Automatically remove local hooksPath overrides",
 		},
+		HomeFlag,
 	},
 	Action: scanLocalAction,
 }
@@ -56,6 +58,28 @@ func scanLocalAction(c *cli.Context) error {
 		return fmt.Errorf("scanning repositories: %w", err)
 	}
 
+	// With directory-scoped activation, overrides outside the scope don't
+	// bypass anything
+	if hooksDir, err := hooksHome(c); err == nil {
+		if scopes, err := scopedDirs(hooksDir); err == nil && len(scopes) > 0 {
+			fmt.Printf("git-hooks is scoped to: %s\n", strings.Join(scopes, ", "))
+
+			var inScopeRepos []cleangit.Repository
+			for _, repo := range repos {
+				if inScope(repo.Path, scopes) {
+					inScopeRepos = append(inScopeRepos, repo)
+				} else if verbose {
+					fmt.Printf("  ⏭️  %s (outside git-hooks scope, ignored)\n", repo.Path)
+				}
+			}
+			if skipped := len(repos) - len(inScopeRepos); skipped > 0 {
+				fmt.Printf("Ignored %d %s outside the git-hooks scope\n",
+					skipped, plural.Of(skipped, "repository", "repositories"))
+			}
+			repos = inScopeRepos
+		}
+	}
+
 	if len(repos) == 0 {
 		fmt.Println("\n✅ No repositories found with local hooksPath overrides.")
 		return nil
@@ -64,7 +88,7 @@ func scanLocalAction(c *cli.Context) error {
 	// Show found repositories
 	fmt.Printf("\nFound %d %s with local hook overrides:\n\n",
 		len(repos),
-		pluralize("repository", "repositories", len(repos)))
+		plural.Of(len(repos), "repository", "repositories"))
 
 	for _, repo := range repos {
 		if verbose {
@@ -80,7 +104,7 @@ func scanLocalAction(c *cli.Context) error {
 		fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		fmt.Printf("Summary: %d %s with local overrides\n",
 			len(repos),
-			pluralize("repository", "repositories", len(repos)))
+			plural.Of(len(repos), "repository", "repositories"))
 		fmt.Println("\n💡 Tip: Use --auto-fix to remove these overrides")
 		return nil
 	}
@@ -132,7 +156,7 @@ func scanLocalAction(c *cli.Context) error {
 	if summary.ConfigsRemoved == summary.RepositoriesWithConfig {
 		fmt.Printf("Summary: %d %s removed successfully\n",
 			summary.ConfigsRemoved,
-			pluralize("override", "overrides", summary.ConfigsRemoved))
+			plural.Of(summary.ConfigsRemoved, "override", "overrides"))
 	} else {
 		fmt.Printf("Summary: %d removed, %d failed\n",
 			summary.ConfigsRemoved,
@@ -142,10 +166,3 @@ func scanLocalAction(c *cli.Context) error {
 
 	return nil
 }
-
-func pluralize(singular, plural string, count int) string {
-	if count == 1 {
-		return singular
-	}
-	return plural
-}
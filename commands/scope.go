@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+)
+
+// scopeFileName is the conditional include file that sets core.hooksPath for
+// directory-scoped activation
+const scopeFileName = "scope.gitconfig"
+
+func scopeFile(hooksDir string) string {
+	return filepath.Join(hooksDir, scopeFileName)
+}
+
+// scopedDirs returns the directories git-hooks is activated for through
+// `includeIf "gitdir:<dir>"` entries in the global config
+func scopedDirs(hooksDir string) ([]string, error) {
+	pairs, err := gitconfig.GetRegexp(".", gitconfig.ScopeGlobal, `^includeif\.gitdir:.*\.path$`)
+	if err != nil {
+		return nil, err
+	}
+
+	file := scopeFile(hooksDir)
+	var dirs []string
+	for _, pair := range pairs {
+		if filepath.Clean(pair.Value) != file {
+			continue
+		}
+		dir := strings.TrimSuffix(strings.TrimPrefix(pair.Key, "includeif.gitdir:"), ".path")
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// addScopes writes the include file and activates it for every dir
+func addScopes(hooksDir string, dirs []string) error {
+	content := fmt.Sprintf("# Generated by git-hooks, included for: %s\n[core]\n\thooksPath = %s\n",
+		strings.Join(dirs, ", "), hooksDir)
+	if err := os.WriteFile(scopeFile(hooksDir), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("writing scope include file: %w", err)
+	}
+
+	for _, dir := range dirs {
+		key := "includeIf.gitdir:" + dir + ".path"
+		if err := gitconfig.Set(".", gitconfig.ScopeGlobal, key, scopeFile(hooksDir)); err != nil {
+			return fmt.Errorf("scoping git-hooks to %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// removeScopes deactivates git-hooks for every dir
+func removeScopes(dirs []string) error {
+	for _, dir := range dirs {
+		if err := gitconfig.RemoveSection(".", gitconfig.ScopeGlobal, "includeIf.gitdir:"+dir); err != nil {
+			return fmt.Errorf("removing git-hooks scope %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// normalizeScopeDir turns dir into the absolute, slash-terminated form that
+// makes `gitdir:` match every repository below it
+func normalizeScopeDir(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(dir, "/") + "/", nil
+}
+
+// inScope reports whether path lies below one of dirs
+func inScope(path string, dirs []string) bool {
+	path = strings.TrimSuffix(path, "/") + "/"
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
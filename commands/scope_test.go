@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeScopeDir(t *testing.T) {
+	home := setupHome(t)
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{name: "adds a trailing slash", dir: "/src/work", want: "/src/work/"},
+		{name: "keeps a trailing slash", dir: "/src/work/", want: "/src/work/"},
+		{name: "cleans the path", dir: "/src/../work//", want: "/work/"},
+		{name: "expands the home", dir: "~", want: home + "/"},
+		{name: "expands below the home", dir: "~/work", want: filepath.Join(home, "work") + "/"},
+		{name: "resolves relative paths", dir: "work", want: filepath.Join(home, "work") + "/"},
+		{name: "leaves other users alone", dir: "~bob/work", want: filepath.Join(home, "~bob", "work") + "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeScopeDir(tt.dir)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInScope(t *testing.T) {
+	dirs := []string{"/src/work/", "/opt/oss/"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/src/work", want: true},
+		{path: "/src/work/", want: true},
+		{path: "/src/work/repo", want: true},
+		{path: "/opt/oss/a/b", want: true},
+		{path: "/src/workshop", want: false},
+		{path: "/src", want: false},
+		{path: "/home/user/repo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			require.Equal(t, tt.want, inScope(tt.path, dirs))
+		})
+	}
+}
+
+func TestConfig_ScopeDir(t *testing.T) {
+	home := setupHome(t)
+	hooksDir := filepath.Join(home, ".config", "git-hooks")
+	work := filepath.Join(home, "work") + "/"
+	oss := filepath.Join(home, "oss") + "/"
+
+	for _, dir := range []string{"work/repo", "oss/repo", "other/repo"} {
+		git(t, home, "init", "-q", filepath.Join(home, dir))
+	}
+
+	output, err := run(t, "config", "--scope-dir", "~/work")
+	require.NoError(t, err, output)
+	require.Empty(t, gitConfig(t, "--global", "core.hooksPath"), "no global activation")
+	require.Equal(t, []string{filepath.Join(hooksDir, scopeFileName)}, gitConfig(t, "--global", "includeIf.gitdir:"+work+".path"))
+	require.FileExists(t, filepath.Join(hooksDir, scopeFileName))
+
+	t.Log("Only repositories below the scope use the hooks")
+	require.Equal(t, hooksDir, repoHooksPath(t, filepath.Join(home, "work", "repo")))
+	require.Empty(t, repoHooksPath(t, filepath.Join(home, "other", "repo")))
+
+	t.Log("Adding the same scope again is a no-op")
+	output, err = run(t, "config", "--scope-dir", "work/")
+	require.NoError(t, err, output)
+	dirs, err := scopedDirs(hooksDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{work}, dirs)
+
+	t.Log("Adding another scope keeps the first one")
+	output, err = run(t, "config", "--scope-dir", oss)
+	require.NoError(t, err, output)
+	dirs, err = scopedDirs(hooksDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{work, oss}, dirs)
+	require.Equal(t, hooksDir, repoHooksPath(t, filepath.Join(home, "oss", "repo")))
+
+	t.Log("Removing one scope keeps the other")
+	output, err = run(t, "implode", "--scope-dir", "~/work")
+	require.NoError(t, err, output)
+	dirs, err = scopedDirs(hooksDir)
+	require.NoError(t, err)
+	require.Equal(t, []string{oss}, dirs)
+	require.Empty(t, repoHooksPath(t, filepath.Join(home, "work", "repo")))
+	require.Equal(t, hooksDir, repoHooksPath(t, filepath.Join(home, "oss", "repo")))
+
+	t.Log("Imploding removes every scope and the include file")
+	output, err = run(t, "implode", "--yes")
+	require.NoError(t, err, output)
+	require.Contains(t, output, "Removed git-hooks scopes: "+oss)
+	require.Empty(t, gitConfig(t, "--global", "includeIf.gitdir:"+oss+".path"))
+	require.NoFileExists(t, filepath.Join(hooksDir, scopeFileName))
+	require.Empty(t, repoHooksPath(t, filepath.Join(home, "oss", "repo")))
+}
+
+// repoHooksPath returns the core.hooksPath git sees in repo
+func repoHooksPath(t *testing.T, repo string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", "config", "core.hooksPath")
+	cmd.Dir = repo
+	output, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return ""
+	}
+	require.NoError(t, err)
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rudderlabs/git-hooks/internal/attest"
+	"github.com/urfave/cli/v2"
+)
+
+var VerifyAttestation = &cli.Command{
+	Name:      "verify-attestation",
+	Usage:     "Check the gitleaks attestations in refs/notes/git-hooks against the commits' trees",
+	ArgsUsage: "[<rev-range>...]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Output the results as JSON",
+		},
+		&cli.BoolFlag{
+			Name:  "require-signature",
+			Usage: "Fail for unsigned attestations and signatures not in gpg.ssh.allowedSignersFile",
+		},
+	},
+	Action: verifyAttestationAction,
+}
+
+func verifyAttestationAction(c *cli.Context) error {
+	revisions := c.Args().Slice()
+	if len(revisions) == 0 {
+		revisions = []string{"--max-count=1", "HEAD"}
+	}
+
+	report, err := attest.Verify(".", revisions, attest.VerifierFromConfig("."), c.Bool("require-signature"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		encoder := json.NewEncoder(os.Stdout) //nolint:forbidigo
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("encoding report: %w", err)
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if report.Failed() {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rudderlabs/git-hooks/commands/hooks/gitleaks"
+	"github.com/rudderlabs/git-hooks/internal/prepush"
+	"github.com/rudderlabs/git-hooks/internal/scanned"
+	"github.com/urfave/cli/v2"
+)
+
+var VerifyScanned = &cli.Command{
+	Name:      "verify-scanned",
+	Usage:     "Report commits without the gitleaks Scanned-by trailer",
+	ArgsUsage: "<rev-range>... | --pre-push [REMOTE [URL]]",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "text",
+			Usage: "Output format: text, json or github",
+		},
+		&cli.StringFlag{
+			Name:  "key",
+			Value: gitleaks.FooterKey,
+			Usage: "Trailer every commit must have",
+		},
+		&cli.StringSliceFlag{
+			Name:  "allow-author",
+			Usage: "Allow commits by authors matching the glob, in addition to githooks.verifyScanned.allowAuthor",
+		},
+		&cli.StringSliceFlag{
+			Name:  "allow-path",
+			Usage: "Allow commits only touching paths matching the glob, in addition to githooks.verifyScanned.allowPath",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-merges",
+			Usage: "Allow merge commits",
+		},
+		&cli.BoolFlag{
+			Name:  "ignore-repo-config",
+			Usage: "Ignore the allowlist in .git-hooks.yaml, which the verified commits can change",
+		},
+		&cli.BoolFlag{
+			Name:  "pre-push",
+			Usage: "Verify the commits of a push, read from stdin like the pre-push hook",
+		},
+	},
+	Action: verifyScannedAction,
+}
+
+func verifyScannedAction(c *cli.Context) error {
+	format := c.String("format")
+	if format != "text" && format != "json" && format != "github" {
+		return fmt.Errorf("unknown format %q, expected text, json or github", format)
+	}
+
+	allow, err := scanned.LoadAllowlist(".", !c.Bool("ignore-repo-config"))
+	if err != nil {
+		return fmt.Errorf("reading verify-scanned allowlist: %w", err)
+	}
+	allow.Authors = append(allow.Authors, c.StringSlice("allow-author")...)
+	allow.Paths = append(allow.Paths, c.StringSlice("allow-path")...)
+	allow.Merges = allow.Merges || c.Bool("allow-merges")
+
+	ranges, err := revisionRanges(c)
+	if err != nil {
+		return err
+	}
+
+	report := scanned.Report{Key: c.String("key"), Allowed: []scanned.Commit{}, Missing: []scanned.Commit{}}
+	for _, revisions := range ranges {
+		r, err := scanned.Verify(scanned.Options{
+			RepoDir:   ".",
+			Revisions: revisions,
+			Key:       c.String("key"),
+			Allow:     allow,
+		})
+		if err != nil {
+			return err
+		}
+		report.Merge(r)
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout) //nolint:forbidigo
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false) // Authors are `Name <email>`
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("encoding report: %w", err)
+		}
+	case "github":
+		report.WriteGitHub(os.Stdout)
+	default:
+		report.WriteText(os.Stdout)
+	}
+
+	if report.Failed() {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// revisionRanges returns the git log arguments of each range to verify. With
+// --pre-push they come from the ref updates on stdin, one range per ref.
+func revisionRanges(c *cli.Context) ([][]string, error) {
+	if !c.Bool("pre-push") {
+		if c.NArg() == 0 {
+			return nil, fmt.Errorf("expected a revision range, e.g. origin/main..HEAD")
+		}
+		return [][]string{c.Args().Slice()}, nil
+	}
+
+	remote := "origin"
+	if c.NArg() > 0 {
+		remote = c.Args().First()
+	}
+	stdin, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("reading pushed refs: %w", err)
+	}
+
+	var ranges [][]string
+	for _, u := range prepush.Parse(stdin) {
+		if !u.Deleted() {
+			ranges = append(ranges, u.Revisions(".", remote))
+		}
+	}
+	return ranges, nil
+}
@@ -0,0 +1,237 @@
+package attest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+)
+
+// NotesRef is where attestations are stored, one note per commit
+const NotesRef = "refs/notes/git-hooks"
+
+// Namespace is the ssh-keygen signature namespace of attestations
+const Namespace = "git-hooks"
+
+const (
+	header         = "git-hooks attestation v1"
+	signatureBegin = "-----BEGIN SSH SIGNATURE-----"
+)
+
+// ErrNoAttestation is returned for commits without attestation note
+var ErrNoAttestation = errors.New("no attestation")
+
+// Attestation records that a tree was scanned before it was committed
+type Attestation struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	// Config is `sha256:<hex>` of the scanner config, or `default`
+	Config string `json:"config"`
+	// Tree is the staged tree that was scanned
+	Tree      string    `json:"tree"`
+	Findings  int       `json:"findings"`
+	Timestamp time.Time `json:"timestamp"`
+	// Signer is the identity the signature is checked against, usually the
+	// committer email
+	Signer string `json:"signer,omitempty"`
+	// Signature is an armored SSH signature over Payload
+	Signature string `json:"signature,omitempty"`
+}
+
+// Payload returns the signed part of the attestation
+func (a Attestation) Payload() string {
+	var b strings.Builder
+	fmt.Fprintln(&b, header)
+	fmt.Fprintf(&b, "tool %s\n", a.Tool)
+	fmt.Fprintf(&b, "version %s\n", a.Version)
+	fmt.Fprintf(&b, "config %s\n", a.Config)
+	fmt.Fprintf(&b, "tree %s\n", a.Tree)
+	fmt.Fprintf(&b, "findings %d\n", a.Findings)
+	fmt.Fprintf(&b, "timestamp %s\n", a.Timestamp.UTC().Format(time.RFC3339))
+	if a.Signer != "" {
+		fmt.Fprintf(&b, "signer %s\n", a.Signer)
+	}
+	return b.String()
+}
+
+// String returns the note content
+func (a Attestation) String() string {
+	return a.Payload() + a.Signature
+}
+
+// Parse reads an attestation note
+func Parse(note string) (Attestation, error) {
+	var a Attestation
+
+	payload, signature, signed := strings.Cut(note, signatureBegin)
+	if signed {
+		a.Signature = signatureBegin + signature
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(payload))
+	if !scanner.Scan() || scanner.Text() != header {
+		return a, fmt.Errorf("not a git-hooks attestation")
+	}
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		var err error
+		switch key {
+		case "tool":
+			a.Tool = value
+		case "version":
+			a.Version = value
+		case "config":
+			a.Config = value
+		case "tree":
+			a.Tree = value
+		case "findings":
+			a.Findings, err = strconv.Atoi(value)
+		case "timestamp":
+			a.Timestamp, err = time.Parse(time.RFC3339, value)
+		case "signer":
+			a.Signer = value
+		}
+		if err != nil {
+			return a, fmt.Errorf("parsing attestation %s: %w", key, err)
+		}
+	}
+
+	// The signature covers the exact payload, which must round-trip
+	if signed && a.Payload() != payload {
+		return a, fmt.Errorf("attestation payload is not canonical")
+	}
+	return a, nil
+}
+
+// Read returns the attestation note of commit
+func Read(repoDir, commit string) (Attestation, error) {
+	// `notes list` exits with 1 when the commit has no note
+	output, err := git(repoDir, nil, "notes", "--ref="+NotesRef, "list", commit)
+	if gitconfig.ExitCode(err) == 1 {
+		return Attestation{}, ErrNoAttestation
+	}
+	if err != nil {
+		return Attestation{}, err
+	}
+	output, err = git(repoDir, nil, "cat-file", "blob", strings.TrimSpace(string(output)))
+	if err != nil {
+		return Attestation{}, err
+	}
+	return Parse(string(output))
+}
+
+// Write stores a as the attestation note of commit, replacing an older one
+func Write(repoDir, commit string, a Attestation) error {
+	if _, err := git(repoDir, strings.NewReader(a.String()), "notes", "--ref="+NotesRef, "add", "-f", "-F", "-", commit); err != nil {
+		return fmt.Errorf("writing attestation note: %w", err)
+	}
+	return nil
+}
+
+// pendingFile is where pre-commit leaves the attestation for post-commit
+func pendingFile(repoDir, tool string) (string, error) {
+	output, err := git(repoDir, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(strings.TrimSpace(string(output)), "git-hooks", tool+"-attestation"), nil
+}
+
+// Pending is an attestation waiting for the commit it belongs to
+type Pending struct {
+	Attestation
+	// Head is the commit HEAD pointed at when the tree was scanned, empty
+	// on an unborn branch
+	Head string
+}
+
+// pendingHead follows the payload in a pending attestation file
+const pendingHead = "head "
+
+// SavePending keeps p until the commit it belongs to exists
+func SavePending(repoDir string, p Pending) error {
+	path, err := pendingFile(repoDir, p.Tool)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(p.Payload()+pendingHead+p.Head+"\n"), 0o644)
+}
+
+// TakePending returns and removes the attestation saved for tool. ok is
+// false when pre-commit didn't save one, e.g. with `git commit --no-verify`.
+func TakePending(repoDir, tool string) (Pending, bool, error) {
+	path, err := pendingFile(repoDir, tool)
+	if err != nil {
+		return Pending{}, false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Pending{}, false, nil
+	}
+	if err != nil {
+		return Pending{}, false, err
+	}
+	if err := os.Remove(path); err != nil {
+		return Pending{}, false, err
+	}
+
+	payload, head, _ := strings.Cut(string(data), "\n"+pendingHead)
+	a, err := Parse(payload + "\n")
+	if err != nil {
+		return Pending{}, false, err
+	}
+	return Pending{Attestation: a, Head: strings.TrimSpace(head)}, true, nil
+}
+
+// Resolve returns the commit rev names, empty when it doesn't resolve, e.g.
+// HEAD on an unborn branch
+func Resolve(repoDir, rev string) string {
+	output, err := git(repoDir, nil, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Tree returns the tree of rev
+func Tree(repoDir, rev string) (string, error) {
+	output, err := git(repoDir, nil, "rev-parse", rev+"^{tree}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// StagedTree returns the tree the index would be committed as
+func StagedTree(repoDir string) (string, error) {
+	output, err := git(repoDir, nil, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("writing staged tree: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func git(dir string, stdin *strings.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
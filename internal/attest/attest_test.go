@@ -0,0 +1,193 @@
+package attest_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rudderlabs/git-hooks/internal/attest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	a := attest.Attestation{
+		Tool:      "gitleaks",
+		Version:   "v8.18.0",
+		Config:    "default",
+		Tree:      "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Findings:  2,
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Signer:    "test@example.com",
+		Signature: "-----BEGIN SSH SIGNATURE-----\nAAAA\n-----END SSH SIGNATURE-----\n",
+	}
+
+	parsed, err := attest.Parse(a.String())
+	require.NoError(t, err)
+	require.Equal(t, a, parsed)
+
+	_, err = attest.Parse("Scanned-by: gitleaks v8.18.0\n")
+	require.Error(t, err)
+
+	// A signed payload must be canonical, or the signature could cover other data
+	_, err = attest.Parse(strings.Replace(a.String(), "findings 2\n", "findings 2\nextra x\n", 1))
+	require.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	repo := setupRepo(t)
+	commit := commitFile(t, repo, "a.txt")
+	tree, err := attest.Tree(repo, commit)
+	require.NoError(t, err)
+
+	res := attest.Check(repo, commit, attest.Verifier{}, false)
+	require.False(t, res.OK)
+	require.Contains(t, res.Message, "no attestation")
+
+	a := attest.Attestation{Tool: "gitleaks", Version: "v8.18.0", Config: "default", Tree: tree, Timestamp: time.Now()}
+	require.NoError(t, attest.Write(repo, commit, a))
+	res = attest.Check(repo, commit, attest.Verifier{}, false)
+	require.True(t, res.OK, res.Message)
+	require.Contains(t, res.Message, "unsigned")
+
+	res = attest.Check(repo, commit, attest.Verifier{}, true)
+	require.False(t, res.OK)
+	require.Contains(t, res.Message, "not signed")
+
+	a.Findings = 1
+	require.NoError(t, attest.Write(repo, commit, a))
+	res = attest.Check(repo, commit, attest.Verifier{}, false)
+	require.False(t, res.OK)
+	require.Contains(t, res.Message, "1 finding")
+
+	// An attestation copied to a commit with another tree doesn't count
+	other := commitFile(t, repo, "b.txt")
+	a.Findings = 0
+	require.NoError(t, attest.Write(repo, other, a))
+	res = attest.Check(repo, other, attest.Verifier{}, false)
+	require.False(t, res.OK)
+	require.Contains(t, res.Message, "attestation is for tree")
+
+	report, err := attest.Verify(repo, []string{"HEAD"}, attest.Verifier{}, false)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 2)
+	require.True(t, report.Failed())
+}
+
+func TestPending(t *testing.T) {
+	repo := setupRepo(t)
+	require.Empty(t, attest.Resolve(repo, "HEAD"), "unborn branch")
+	commitFile(t, repo, "a.txt")
+
+	_, ok, err := attest.TakePending(repo, "gitleaks")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	a := attest.Pending{
+		Attestation: attest.Attestation{Tool: "gitleaks", Version: "v8.18.0", Config: "default", Tree: "abc", Timestamp: time.Now().UTC().Truncate(time.Second)},
+		Head:        attest.Resolve(repo, "HEAD"),
+	}
+	require.NotEmpty(t, a.Head)
+	require.NoError(t, attest.SavePending(repo, a))
+
+	taken, ok, err := attest.TakePending(repo, "gitleaks")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, a, taken)
+
+	_, ok, err = attest.TakePending(repo, "gitleaks")
+	require.NoError(t, err)
+	require.False(t, ok, "pending attestation is removed once taken")
+}
+
+func TestSignAndVerify(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	repo := setupRepo(t)
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	run(t, repo, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key)
+	git(t, repo, "config", "user.signingKey", key)
+
+	signer, ok, err := attest.SignerFromConfig(repo)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	commit := commitFile(t, repo, "a.txt")
+	tree, err := attest.Tree(repo, commit)
+	require.NoError(t, err)
+	a := attest.Attestation{Tool: "gitleaks", Version: "v8.18.0", Config: "default", Tree: tree, Timestamp: time.Now()}
+	require.NoError(t, signer.Sign(&a))
+	require.Equal(t, "test@example.com", a.Signer)
+	require.NoError(t, attest.Write(repo, commit, a))
+
+	// Without allowed signers the signature is valid but not trusted
+	res := attest.Check(repo, commit, attest.VerifierFromConfig(repo), false)
+	require.True(t, res.OK, res.Message)
+	require.Contains(t, res.Message, "not checked")
+	res = attest.Check(repo, commit, attest.VerifierFromConfig(repo), true)
+	require.False(t, res.OK)
+
+	publicKey, err := os.ReadFile(key + ".pub")
+	require.NoError(t, err)
+	allowed := filepath.Join(t.TempDir(), "allowed_signers")
+	require.NoError(t, os.WriteFile(allowed, []byte("test@example.com "+string(publicKey)), 0o644))
+	git(t, repo, "config", "gpg.ssh.allowedSignersFile", allowed)
+
+	res = attest.Check(repo, commit, attest.VerifierFromConfig(repo), true)
+	require.True(t, res.OK, res.Message)
+	require.Contains(t, res.Message, "signed by test@example.com")
+
+	// Tampering with the note breaks the signature
+	a.Findings = 0
+	a.Version = "v0.0.0"
+	require.NoError(t, attest.Write(repo, commit, a))
+	res = attest.Check(repo, commit, attest.VerifierFromConfig(repo), false)
+	require.False(t, res.OK)
+	require.Contains(t, res.Message, "bad signature")
+}
+
+// Helper functions
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := filepath.Join(home, "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	git(t, repo, "init", "-q", "-b", "main")
+	git(t, repo, "config", "user.name", "Test User")
+	git(t, repo, "config", "user.email", "test@example.com")
+	return repo
+}
+
+// commitFile creates file and commits it, returning the commit SHA
+func commitFile(t *testing.T, repo, file string) string {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, file), []byte(file+"\n"), 0o644))
+	git(t, repo, "add", file)
+	git(t, repo, "commit", "-q", "-m", "add "+file)
+	return strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	return run(t, dir, "git", args...)
+}
+
+func run(t *testing.T, dir, name string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "%s %v: %s", name, args, output)
+	return string(output)
+}
@@ -0,0 +1,135 @@
+package attest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+)
+
+// Signer signs attestations with the user's SSH signing key, configured like
+// for `git commit -S` with gpg.format=ssh
+type Signer struct {
+	// Key is user.signingKey: a key file, or `key::` followed by a public key
+	// held by ssh-agent
+	Key string
+	// Program is gpg.ssh.program
+	Program string
+	// Identity is recorded as the attestation signer
+	Identity string
+}
+
+// Verifier checks attestation signatures
+type Verifier struct {
+	// AllowedSigners is gpg.ssh.allowedSignersFile. Without it signatures
+	// are only checked to be valid, not trusted.
+	AllowedSigners string
+	Program        string
+}
+
+// SignerFromConfig returns the signer configured for repoDir. ok is false
+// when no user.signingKey is set.
+func SignerFromConfig(repoDir string) (Signer, bool, error) {
+	var s Signer
+	key, ok, err := gitconfig.Get(repoDir, gitconfig.ScopeDefault, "user.signingKey")
+	if err != nil || !ok || key == "" {
+		return s, false, err
+	}
+	s.Key = expandHome(key)
+	s.Program = sshProgram(repoDir)
+	s.Identity, _, _ = gitconfig.Get(repoDir, gitconfig.ScopeDefault, "user.email")
+	return s, true, nil
+}
+
+// VerifierFromConfig returns the verifier configured for repoDir
+func VerifierFromConfig(repoDir string) Verifier {
+	v := Verifier{Program: sshProgram(repoDir)}
+	if file, ok, err := gitconfig.Get(repoDir, gitconfig.ScopeDefault, "gpg.ssh.allowedSignersFile"); err == nil && ok {
+		v.AllowedSigners = expandHome(file)
+	}
+	return v
+}
+
+// Sign sets the signer and signature of a
+func (s Signer) Sign(a *Attestation) error {
+	a.Signer = s.Identity
+	a.Signature = ""
+
+	dir, err := os.MkdirTemp("", "git-hooks-sign")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"-Y", "sign", "-n", Namespace}
+	if literal, ok := strings.CutPrefix(s.Key, "key::"); ok {
+		// A public key, the private one is in ssh-agent
+		keyFile := filepath.Join(dir, "key.pub")
+		if err := os.WriteFile(keyFile, []byte(literal+"\n"), 0o600); err != nil {
+			return err
+		}
+		args = append(args, "-U", "-f", keyFile)
+	} else {
+		args = append(args, "-f", s.Key)
+	}
+
+	cmd := exec.Command(s.Program, args...)
+	cmd.Stdin = strings.NewReader(a.Payload())
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	signature, err := cmd.Output()
+	if err != nil {
+		a.Signer = ""
+		return fmt.Errorf("signing attestation with %s: %w: %s", s.Key, err, strings.TrimSpace(stderr.String()))
+	}
+	a.Signature = string(signature)
+	return nil
+}
+
+// Verify checks the signature of a. trusted is false when the signature is
+// valid but there is no allowed signers file to trust the key.
+func (v Verifier) Verify(a Attestation) (trusted bool, err error) {
+	if a.Signature == "" {
+		return false, fmt.Errorf("attestation is not signed")
+	}
+
+	dir, err := os.MkdirTemp("", "git-hooks-verify")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+	sigFile := filepath.Join(dir, "attestation.sig")
+	if err := os.WriteFile(sigFile, []byte(a.Signature), 0o600); err != nil {
+		return false, err
+	}
+
+	args := []string{"-Y", "check-novalidate", "-n", Namespace, "-s", sigFile}
+	if v.AllowedSigners != "" {
+		args = []string{"-Y", "verify", "-f", v.AllowedSigners, "-I", a.Signer, "-n", Namespace, "-s", sigFile}
+	}
+	cmd := exec.Command(v.Program, args...)
+	cmd.Stdin = strings.NewReader(a.Payload())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("bad signature: %s", strings.TrimSpace(string(output)))
+	}
+	return v.AllowedSigners != "", nil
+}
+
+func sshProgram(repoDir string) string {
+	if program, ok, err := gitconfig.Get(repoDir, gitconfig.ScopeDefault, "gpg.ssh.program"); err == nil && ok && program != "" {
+		return program
+	}
+	return "ssh-keygen"
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
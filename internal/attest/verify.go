@@ -0,0 +1,113 @@
+package attest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/plural"
+)
+
+// Result is the outcome of checking the attestation of one commit
+type Result struct {
+	Commit      string       `json:"commit"`
+	OK          bool         `json:"ok"`
+	Message     string       `json:"message"`
+	Attestation *Attestation `json:"attestation,omitempty"`
+}
+
+// Report lists the results of all checked commits
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Failed reports whether any commit failed verification
+func (r Report) Failed() bool {
+	for _, res := range r.Results {
+		if !res.OK {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify checks the attestations of the commits selected by revisions, git
+// rev-list arguments
+func Verify(repoDir string, revisions []string, v Verifier, requireSignature bool) (Report, error) {
+	report := Report{Results: []Result{}}
+	output, err := git(repoDir, nil, append(append([]string{"rev-list"}, revisions...), "--")...)
+	if err != nil {
+		return report, fmt.Errorf("listing commits of %s: %w", strings.Join(revisions, " "), err)
+	}
+	for _, commit := range strings.Fields(string(output)) {
+		report.Results = append(report.Results, Check(repoDir, commit, v, requireSignature))
+	}
+	return report, nil
+}
+
+// Check verifies that commit has an attestation for its own tree without
+// findings, signed when requireSignature is set
+func Check(repoDir, commit string, v Verifier, requireSignature bool) Result {
+	res := Result{Commit: commit}
+
+	a, err := Read(repoDir, commit)
+	if errors.Is(err, ErrNoAttestation) {
+		res.Message = "no attestation in " + NotesRef
+		return res
+	}
+	if err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	res.Attestation = &a
+
+	tree, err := Tree(repoDir, commit)
+	if err != nil {
+		res.Message = err.Error()
+		return res
+	}
+	switch {
+	case a.Tree != tree:
+		res.Message = fmt.Sprintf("attestation is for tree %s, the commit has tree %s", a.Tree, tree)
+		return res
+	case a.Findings > 0:
+		res.Message = fmt.Sprintf("%s %s reported %d %s", a.Tool, a.Version, a.Findings, plural.Of(a.Findings, "finding", "findings"))
+		return res
+	}
+
+	scan := fmt.Sprintf("scanned by %s %s on %s", a.Tool, a.Version, a.Timestamp.Format("2006-01-02 15:04"))
+	switch {
+	case a.Signature == "" && requireSignature:
+		res.Message = scan + ", but the attestation is not signed"
+	case a.Signature == "":
+		res.OK, res.Message = true, scan+", unsigned"
+	default:
+		trusted, err := v.Verify(a)
+		switch {
+		case err != nil:
+			res.Message = fmt.Sprintf("%s, %v", scan, err)
+		case trusted:
+			res.OK, res.Message = true, fmt.Sprintf("%s, signed by %s", scan, a.Signer)
+		default:
+			res.OK = !requireSignature
+			res.Message = fmt.Sprintf("%s, signed by %s (not checked, set gpg.ssh.allowedSignersFile)", scan, a.Signer)
+		}
+	}
+	return res
+}
+
+// WriteText renders the report for humans
+func (r Report) WriteText(w io.Writer) {
+	for _, res := range r.Results {
+		icon := "✅"
+		if !res.OK {
+			icon = "❌"
+		}
+		short := res.Commit
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", icon, short, res.Message)
+	}
+}
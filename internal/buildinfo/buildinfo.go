@@ -0,0 +1,18 @@
+package buildinfo
+
+import "runtime/debug"
+
+// version is set at build time via -ldflags
+var version string
+
+// Version returns the git-hooks version, falling back to the module version
+// recorded by `go install` and finally to "dev"
+func Version() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
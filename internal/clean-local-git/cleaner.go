@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/shim"
 )
 
 var (
@@ -25,6 +27,9 @@ type Repository struct {
 	ConfigPath      string
 	CustomHooksPath string
 	HasCustomHooks  bool
+	// Managed is set when the custom hooksPath points at git-hooks shims,
+	// e.g. after `git-hooks config --local`
+	Managed bool
 }
 
 // Result tracks the outcome of removing hooksPath config
@@ -42,7 +47,9 @@ type Summary struct {
 	Results                []Result
 }
 
-// Scan finds Git repositories and returns those with custom hooksPath
+// Scan finds Git repositories and returns those with a custom hooksPath that
+// bypasses git-hooks. Repositories whose hooksPath points at git-hooks shims
+// are compliant and not returned.
 func Scan(ctx context.Context, rootPath string, maxDepth int) ([]Repository, error) {
 	repos, err := findRepositories(ctx, rootPath, maxDepth)
 	if err != nil {
@@ -52,7 +59,7 @@ func Scan(ctx context.Context, rootPath string, maxDepth int) ([]Repository, err
 	// Filter to only return repos with custom hooks
 	var reposWithHooks []Repository
 	for _, repo := range repos {
-		if repo.HasCustomHooks {
+		if repo.HasCustomHooks && !repo.Managed {
 			reposWithHooks = append(reposWithHooks, repo)
 		}
 	}
@@ -60,6 +67,23 @@ func Scan(ctx context.Context, rootPath string, maxDepth int) ([]Repository, err
 	return reposWithHooks, nil
 }
 
+// Open returns the repository at repoPath, which may be any directory inside
+// its working tree
+func Open(repoPath string) (Repository, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel", "--absolute-git-dir")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return Repository{}, fmt.Errorf("%s: %w", repoPath, ErrNotGitRepository)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		return Repository{}, fmt.Errorf("%s: %w", repoPath, ErrNotGitRepository)
+	}
+	return createRepository(lines[0], lines[1]), nil
+}
+
 // Clean removes hooksPath configuration from repositories
 func Clean(ctx context.Context, repos []Repository) Summary {
 	sum := Summary{
@@ -191,10 +215,39 @@ func createRepository(repoPath, gitDir string) Repository {
 	customPath, hasCustom := getHooksPath(configPath)
 	repo.CustomHooksPath = customPath
 	repo.HasCustomHooks = hasCustom
+	repo.Managed = hasCustom && isManagedHooksPath(repoPath, customPath)
 
 	return repo
 }
 
+// isManagedHooksPath reports whether hooksPath contains git-hooks shims
+func isManagedHooksPath(repoPath, hooksPath string) bool {
+	if strings.HasPrefix(hooksPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		hooksPath = filepath.Join(home, hooksPath[2:])
+	}
+	if !filepath.IsAbs(hooksPath) {
+		hooksPath = filepath.Join(repoPath, hooksPath)
+	}
+
+	entries, err := os.ReadDir(hooksPath)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, err := shim.Read(filepath.Join(hooksPath, entry.Name())); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 // getHooksPath extracts hooksPath from .git/config using git config command
 func getHooksPath(configPath string) (string, bool) {
 	// Get the repository path (parent of .git directory)
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	cleangit "github.com/rudderlabs/git-hooks/internal/clean-local-git"
+	"github.com/rudderlabs/git-hooks/internal/shim"
 	"github.com/stretchr/testify/require"
 )
 
@@ -369,6 +370,48 @@ func TestScan_NonGitDirectory(t *testing.T) {
 	require.Len(t, repos, 0)
 }
 
+func TestScan_ManagedLocalHooksPath(t *testing.T) {
+	t.Log("Testing Scan treats hooksPath pointing at git-hooks shims as compliant")
+
+	tempDir := t.TempDir()
+	shimsDir := filepath.Join(tempDir, "shims")
+	require.NoError(t, os.MkdirAll(shimsDir, 0o755))
+	require.NoError(t, shim.Write(shimsDir, "pre-commit", shim.Params{BinaryPath: "/usr/local/bin/git-hooks", Version: "v1.0.0"}))
+
+	managed := filepath.Join(tempDir, "managed")
+	setupGitRepo(t, managed)
+	setGitConfig(t, managed, "core.hooksPath", shimsDir)
+
+	husky := filepath.Join(tempDir, "husky")
+	setupGitRepo(t, husky)
+	setGitConfig(t, husky, "core.hooksPath", ".husky")
+
+	repos, err := cleangit.Scan(context.Background(), tempDir, 2)
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	require.Equal(t, husky, repos[0].Path)
+}
+
+func TestOpen(t *testing.T) {
+	t.Log("Testing Open resolves a repository from any directory inside it")
+
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	setupGitRepo(t, tempDir)
+	setGitConfig(t, tempDir, "core.hooksPath", ".husky")
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "src"), 0o755))
+
+	repo, err := cleangit.Open(filepath.Join(tempDir, "src"))
+	require.NoError(t, err)
+	require.Equal(t, tempDir, repo.Path)
+	require.Equal(t, filepath.Join(tempDir, ".git"), repo.GitDir)
+	require.True(t, repo.HasCustomHooks)
+	require.Equal(t, ".husky", repo.CustomHooksPath)
+
+	_, err = cleangit.Open(t.TempDir())
+	require.ErrorIs(t, err, cleangit.ErrNotGitRepository)
+}
+
 // Helper functions
 
 // setupGitRepo creates a real git repository using git init
@@ -0,0 +1,356 @@
+package dispatch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/glob"
+	"github.com/rudderlabs/git-hooks/internal/prepush"
+)
+
+// Condition names, used both as git config keys and as script header fields
+const (
+	CondBranch    = "branch"
+	CondRemote    = "remote"
+	CondRemoteURL = "remote-url"
+	CondRef       = "ref"
+	CondPaths     = "paths"
+)
+
+var conditionNames = []string{CondBranch, CondRemote, CondRemoteURL, CondRef, CondPaths}
+
+// headerPrefix starts a condition line in a script's leading comment block,
+// e.g. `# git-hooks-branch: main release/*`
+const headerPrefix = "# git-hooks-"
+
+// Conditions restrict when a script runs. Each maps a condition name to glob
+// patterns; a condition holds when any pattern matches and a script runs
+// when all its conditions hold.
+type Conditions map[string][]string
+
+// requiredField marks a system or global script that repositories can't
+// disable, e.g. `# git-hooks-required: true`
+const requiredField = "required"
+
+// severityField sets how a script's failure is treated, e.g.
+// `# git-hooks-severity: warn`
+const severityField = "severity"
+
+var settingNames = append([]string{requiredField, severityField}, conditionNames...)
+
+// settings are the per-script options from a script header or git config
+type settings struct {
+	conditions Conditions
+	required   bool
+	severity   string
+}
+
+func (s *settings) set(name, value string) {
+	switch name {
+	case requiredField:
+		required, err := strconv.ParseBool(strings.TrimSpace(value))
+		s.required = s.required || err == nil && required
+		return
+	case severityField:
+		s.severity = strings.TrimSpace(value)
+		return
+	}
+	if s.conditions == nil {
+		s.conditions = Conditions{}
+	}
+	s.conditions[name] = append(s.conditions[name], strings.Fields(value)...)
+}
+
+// readSettings parses the fields in the leading comment block of the script
+// at path
+func readSettings(path string) (settings, error) {
+	var s settings
+	f, err := os.Open(path)
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#!") {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break // End of the header block
+		}
+		if !strings.HasPrefix(line, headerPrefix) {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, headerPrefix), ":")
+		if !ok || !isSetting(name) {
+			continue
+		}
+		s.set(name, value)
+	}
+	return s, scanner.Err()
+}
+
+// configSetting is one `githooks.<hook>.<script>.<setting>` entry of git
+// config and the scope that set it
+type configSetting struct {
+	scope gitconfig.Scope
+	name  string
+	value string
+}
+
+// configSettings returns the settings in git config as
+// `githooks.<hook>.<script>.<setting>`, keyed by script name in the order git
+// applies them
+func configSettings(h Hook) (map[string][]configSetting, error) {
+	pattern := `^githooks\.` + regexp.QuoteMeta(h.Name) + `\..+\.(` + strings.Join(settingNames, "|") + `)$`
+	pairs, err := gitconfig.GetRegexp(h.RepoDir, gitconfig.ScopeDefault, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	byScript := map[string][]configSetting{}
+	prefix := "githooks." + h.Name + "."
+	for _, pair := range pairs {
+		rest := strings.TrimPrefix(pair.Key, prefix)
+		i := strings.LastIndex(rest, ".")
+		script := rest[:i]
+		byScript[script] = append(byScript[script], configSetting{scope: pair.Scope, name: rest[i+1:], value: pair.Value})
+	}
+	return byScript, nil
+}
+
+// scopeRank orders config scopes from the machine down to the repository.
+// Settings from a scope below the one that required a script can't weaken it.
+func scopeRank(scope gitconfig.Scope) int {
+	switch scope {
+	case gitconfig.ScopeSystem:
+		return 0
+	case gitconfig.ScopeGlobal:
+		return 1
+	}
+	return 2
+}
+
+func isSetting(name string) bool {
+	for _, c := range settingNames {
+		if name == c {
+			return true
+		}
+	}
+	return false
+}
+
+// facts are the properties of the current invocation conditions are checked
+// against. They are computed on first use since most scripts have none.
+type facts struct {
+	h     Hook
+	stdin []byte
+
+	branch     *string
+	updates    *[]prepush.Update
+	paths      *[]string
+	pathsError error
+}
+
+// skip returns why the script must not run, or "" when all its conditions hold
+func (f *facts) skip(conds Conditions) (string, error) {
+	for _, name := range conditionNames {
+		patterns := conds[name]
+		if len(patterns) == 0 {
+			continue
+		}
+
+		values, err := f.values(name)
+		if err != nil {
+			return "", err
+		}
+		if values == nil {
+			return fmt.Sprintf("%s condition does not apply to %s", name, f.h.Name), nil
+		}
+		if !anyMatch(name, patterns, values) {
+			return fmt.Sprintf("%s %s does not match %s", name, describe(values), strings.Join(patterns, " ")), nil
+		}
+	}
+	return "", nil
+}
+
+// values returns the values to match for a condition, or nil when the
+// condition can't be evaluated for this hook
+func (f *facts) values(name string) ([]string, error) {
+	switch name {
+	case CondBranch:
+		branch, err := f.currentBranch()
+		if err != nil {
+			return nil, err
+		}
+		return []string{branch}, nil
+	case CondRemote, CondRemoteURL:
+		if f.h.Name != "pre-push" || len(f.h.Args) < 2 {
+			return nil, nil
+		}
+		if name == CondRemote {
+			return []string{f.h.Args[0]}, nil
+		}
+		return []string{f.h.Args[1]}, nil
+	case CondRef:
+		if f.h.Name != "pre-push" {
+			return nil, nil
+		}
+		var refs []string
+		for _, u := range f.refUpdates() {
+			refs = append(refs, u.RemoteRef)
+		}
+		return nonNil(refs), nil
+	case CondPaths:
+		paths, err := f.changedPaths()
+		return nonNil(paths), err
+	}
+	return nil, nil
+}
+
+func (f *facts) currentBranch() (string, error) {
+	if f.branch == nil {
+		output, err := f.git("symbolic-ref", "--quiet", "--short", "HEAD")
+		branch := strings.TrimSpace(string(output))
+		if err != nil {
+			// Detached HEAD has no branch to match
+			if gitconfig.ExitCode(err) != 1 {
+				return "", fmt.Errorf("reading current branch: %w", err)
+			}
+			branch = ""
+		}
+		f.branch = &branch
+	}
+	return *f.branch, nil
+}
+
+func (f *facts) refUpdates() []prepush.Update {
+	if f.updates == nil {
+		updates := prepush.Parse(f.stdin)
+		f.updates = &updates
+	}
+	return *f.updates
+}
+
+// changedPaths returns the files in the pushed commits for pre-push and the
+// staged files otherwise
+func (f *facts) changedPaths() ([]string, error) {
+	if f.pathsError != nil {
+		return nil, f.pathsError
+	}
+	if f.paths != nil {
+		return *f.paths, nil
+	}
+
+	var paths []string
+	if f.h.Name == "pre-push" {
+		for _, u := range f.refUpdates() {
+			if u.Deleted() {
+				continue // Deleting a ref changes no files
+			}
+			args := append([]string{"log", "--name-only", "--format="}, u.Revisions(f.h.RepoDir, f.h.Args[0])...)
+			output, err := f.git(args...)
+			if err != nil {
+				f.pathsError = fmt.Errorf("listing pushed files: %w", err)
+				return nil, f.pathsError
+			}
+			paths = append(paths, lines(output)...)
+		}
+	} else {
+		output, err := f.git("diff", "--cached", "--name-only")
+		if err != nil {
+			f.pathsError = fmt.Errorf("listing staged files: %w", err)
+			return nil, f.pathsError
+		}
+		paths = lines(output)
+	}
+
+	f.paths = &paths
+	return paths, nil
+}
+
+func (f *facts) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = f.h.RepoDir
+	return cmd.Output()
+}
+
+// anyMatch reports whether any value matches any pattern. Refs also match
+// their short branch name, so `main` matches refs/heads/main.
+func anyMatch(name string, patterns, values []string) bool {
+	for _, value := range values {
+		candidates := []string{value}
+		if name == CondRef {
+			candidates = append(candidates, strings.TrimPrefix(value, "refs/heads/"))
+		}
+		for _, pattern := range patterns {
+			for _, candidate := range candidates {
+				if glob.Match(pattern, candidate) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func describe(values []string) string {
+	switch {
+	case len(values) == 0 || len(values) == 1 && values[0] == "":
+		return "(none)"
+	case len(values) > 3:
+		return strings.Join(values[:3], ", ") + fmt.Sprintf(" and %d more", len(values)-3)
+	}
+	return strings.Join(values, ", ")
+}
+
+// lines splits command output, keeping file names with spaces intact
+func lines(output []byte) []string {
+	var out []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// nonNil distinguishes "nothing changed" from "not applicable"
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// readStdin buffers stdin for hooks that receive input, so every script sees
+// all of it
+func readStdin(h Hook) ([]byte, error) {
+	if h.Stdin == nil || !stdinHooks[h.Name] {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(h.Stdin); err != nil {
+		return nil, fmt.Errorf("reading %s input: %w", h.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// stdinHooks are the hooks git feeds input on stdin
+var stdinHooks = map[string]bool{
+	"pre-push":              true,
+	"pre-receive":           true,
+	"post-receive":          true,
+	"post-rewrite":          true,
+	"reference-transaction": true,
+	"proc-receive":          true,
+}
@@ -0,0 +1,452 @@
+package dispatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/husky"
+	"github.com/rudderlabs/git-hooks/internal/repoconfig"
+	"github.com/rudderlabs/git-hooks/internal/trailers"
+)
+
+// Level is a source of hook scripts
+type Level string
+
+const (
+	LevelSystem   Level = "system"
+	LevelGlobal   Level = "global"
+	LevelLocal    Level = "local"
+	LevelConfig   Level = "config"
+	LevelHusky    Level = "husky"
+	LevelStandard Level = "standard"
+)
+
+// Script is a single executable step of a hook
+type Script struct {
+	Name  string
+	Level Level
+	// Path is the file to execute
+	Path string
+	// Command is a shell command, for hooks defined in git config
+	Command string
+	// Argv is a full command line, for scripts that don't take the hook
+	// arguments as is
+	Argv []string
+	// Env is added to the environment
+	Env []string
+	// Conditions restrict when the script runs
+	Conditions Conditions
+	// Required scripts can't be disabled by a repository
+	Required bool
+	// Severity decides whether a failure blocks the hook, empty means error
+	Severity Severity
+}
+
+func (s Script) String() string {
+	return fmt.Sprintf("%s:%s", s.Level, s.Name)
+}
+
+// inherited reports whether the script comes from outside the repository and
+// may be adjusted by its .git-hooks.yaml
+func (s Script) inherited() bool {
+	return s.Level == LevelSystem || s.Level == LevelGlobal || s.Level == LevelConfig
+}
+
+// Chain formats scripts in execution order
+func Chain(scripts []Script) string {
+	if len(scripts) == 0 {
+		return "(no scripts)"
+	}
+	names := make([]string, len(scripts))
+	for i, script := range scripts {
+		names[i] = script.String()
+		if script.Required {
+			names[i] += " (required)"
+		}
+		if script.Severity != "" && script.Severity != SeverityError {
+			names[i] += " (" + string(script.Severity) + ")"
+		}
+	}
+	return strings.Join(names, " -> ")
+}
+
+// Hook describes a single hook invocation
+type Hook struct {
+	Name string
+	Args []string
+
+	// HooksDir is the global git-hooks home
+	HooksDir string
+	// SystemDir is set when invoked through a system-wide shim
+	SystemDir string
+	// RepoDir is the working tree the hook runs in
+	RepoDir string
+	// GitDir is the repository's git directory
+	GitDir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// DefaultOrder is the order levels run in unless configured otherwise
+var DefaultOrder = []Level{LevelSystem, LevelGlobal, LevelLocal, LevelConfig, LevelHusky, LevelStandard}
+
+// Collect returns the scripts to run for the hook, in execution order
+func Collect(h Hook) ([]Script, error) {
+	cfg, err := repoconfig.Load(h.RepoDir)
+	if err != nil {
+		return nil, err
+	}
+	order, disabled, err := levelOrder(h, cfg.Levels)
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []Script
+	for _, level := range order {
+		found, err := collectLevel(h, level)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, found...)
+	}
+
+	configured, err := addSettings(h, scripts)
+	if err != nil {
+		return nil, err
+	}
+	scripts = dropDisabledLevels(h, scripts, disabled)
+
+	hookCfg, ok := cfg.Hooks[h.Name]
+	scripts = compose(h, scripts, hookCfg)
+	if applySeverity(h, scripts, hookCfg) || ok || cfg.Levels.Order != nil || cfg.Levels.Disable != nil {
+		configured = true
+	}
+
+	// Show the chain whenever git config, the repository or the environment
+	// adjusted it
+	if configured {
+		fmt.Fprintf(h.Stderr, "git-hooks: %s: %s\n", h.Name, Chain(scripts))
+	}
+	return scripts, nil
+}
+
+func collectLevel(h Hook, level Level) ([]Script, error) {
+	switch level {
+	case LevelSystem:
+		// System-wide scripts when invoked through a system shim
+		if h.SystemDir != "" && filepath.Clean(h.SystemDir) != filepath.Clean(h.HooksDir) {
+			return scriptsInDir(LevelSystem, filepath.Join(h.SystemDir, h.Name+".d"))
+		}
+	case LevelGlobal:
+		return scriptsInDir(LevelGlobal, filepath.Join(h.HooksDir, h.Name+".d"))
+	case LevelLocal:
+		return scriptsInDir(LevelLocal, filepath.Join(h.RepoDir, ".git-hooks", h.Name+".d"))
+	case LevelConfig:
+		return configCommands(h)
+	case LevelHusky:
+		// Exactly one invocation, depending on the Husky version
+		invocation, ok, err := husky.Resolve(h.RepoDir, h.Name, h.Args)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []Script{{Name: h.Name, Level: LevelHusky, Argv: invocation.Argv, Env: invocation.Env}}, nil
+	case LevelStandard:
+		// Standard Git hook for backwards compatibility. Scripts generated by
+		// Husky v4 are skipped as the husky level already runs their commands.
+		if path := filepath.Join(h.GitDir, "hooks", h.Name); isExecutable(path) && !husky.IsGenerated(path) {
+			return []Script{{Name: h.Name, Level: LevelStandard, Path: path}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// addSettings attaches the conditions, severity and required flag from script
+// headers and git config. Config replaces a header condition of the same
+// name, except for required scripts where only the scope that required them
+// or one above can. No scope can lower the severity of a required script. It
+// reports whether git config changed any script.
+func addSettings(h Hook, scripts []Script) (bool, error) {
+	byScript, err := configSettings(h)
+	if err != nil {
+		return false, err
+	}
+
+	changed := false
+	for i := range scripts {
+		var s settings
+		if scripts[i].Path != "" {
+			if s, err = readSettings(scripts[i].Path); err != nil {
+				return false, fmt.Errorf("reading settings of %s: %w", scripts[i], err)
+			}
+		}
+
+		// The scope that required the script, a header counts as its level
+		requiredBy := scopeRank(gitconfig.ScopeLocal)
+		if s.required {
+			requiredBy = scopeRank(gitconfig.ScopeGlobal)
+			if scripts[i].Level == LevelSystem {
+				requiredBy = scopeRank(gitconfig.ScopeSystem)
+			}
+		}
+		cfg := settings{}
+		for _, entry := range byScript[scripts[i].Name] {
+			if entry.name == requiredField {
+				cfg.set(entry.name, entry.value)
+				if cfg.required && scopeRank(entry.scope) < requiredBy {
+					requiredBy = scopeRank(entry.scope)
+				}
+			}
+		}
+		required := (s.required || cfg.required) && scripts[i].inherited()
+
+		var severityScope gitconfig.Scope
+		for _, entry := range byScript[scripts[i].Name] {
+			switch {
+			case entry.name == requiredField:
+			case entry.name == severityField:
+				cfg.set(entry.name, entry.value)
+				severityScope = entry.scope
+			case required && scopeRank(entry.scope) > requiredBy:
+				fmt.Fprintf(h.Stderr, "git-hooks: %s config cannot change the %s condition of required script %s\n", entry.scope, entry.name, scripts[i])
+			default:
+				cfg.set(entry.name, entry.value)
+			}
+		}
+
+		for name, patterns := range cfg.conditions {
+			if s.conditions == nil {
+				s.conditions = Conditions{}
+			}
+			s.conditions[name] = patterns
+			changed = true
+		}
+		changed = changed || cfg.required || cfg.severity != ""
+		scripts[i].Conditions = s.conditions
+		if s.severity != "" {
+			severity, err := parseSeverity(s.severity)
+			if err != nil {
+				fmt.Fprintf(h.Stderr, "git-hooks: ignoring severity of %s: %v\n", scripts[i], err)
+			}
+			scripts[i].Severity = severity
+		}
+		// Only scripts from outside the repository can be required
+		scripts[i].Required = required
+		if cfg.severity != "" {
+			source := fmt.Sprintf("%s config", severityScope)
+			severity, err := parseSeverity(cfg.severity)
+			if err != nil {
+				fmt.Fprintf(h.Stderr, "git-hooks: %s: ignoring severity of %s: %v\n", source, scripts[i], err)
+				continue
+			}
+			setSeverity(h, scripts[i:i+1], "", severity, source)
+		}
+	}
+	return changed, nil
+}
+
+// compose applies the repository's .git-hooks.yaml, which can disable or
+// override inherited scripts by name
+func compose(h Hook, scripts []Script, hookCfg repoconfig.Hook) []Script {
+	disabled := map[string]bool{}
+	for _, name := range hookCfg.Disable {
+		disabled[name] = true
+	}
+
+	found := map[string]bool{}
+	var composed []Script
+	for _, script := range scripts {
+		command, override := hookCfg.Override[script.Name]
+		if !script.inherited() || !disabled[script.Name] && !override {
+			composed = append(composed, script)
+			continue
+		}
+
+		found[script.Name] = true
+		switch {
+		case script.Required:
+			fmt.Fprintf(h.Stderr, "git-hooks: %s cannot disable required script %s\n", repoconfig.FileName, script)
+			composed = append(composed, script)
+		case disabled[script.Name]:
+			// Dropped from the chain
+		default:
+			composed = append(composed, Script{
+				Name:       script.Name,
+				Level:      LevelLocal,
+				Command:    command,
+				Conditions: script.Conditions,
+				Severity:   script.Severity,
+			})
+		}
+	}
+
+	names := append([]string{}, hookCfg.Disable...)
+	for name := range hookCfg.Override {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !found[name] {
+			fmt.Fprintf(h.Stderr, "git-hooks: %s: no inherited %s script named %q\n", repoconfig.FileName, h.Name, name)
+			found[name] = true
+		}
+	}
+
+	return composed
+}
+
+// Run executes scripts in order and stops at the first failure of a script
+// with error severity. Scripts that are off or whose conditions don't hold
+// are skipped with the reason. Configured trailers are added to the commit
+// message after the scripts of commit-msg and prepare-commit-msg.
+func Run(h Hook, scripts []Script) error {
+	stdin, err := readStdin(h)
+	if err != nil {
+		return err
+	}
+	f := &facts{h: h, stdin: stdin}
+
+	for _, script := range scripts {
+		if script.Severity == SeverityOff {
+			fmt.Fprintf(h.Stderr, "git-hooks: skipping %s: severity is off\n", script)
+			continue
+		}
+		if len(script.Conditions) > 0 {
+			reason, err := f.skip(script.Conditions)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				fmt.Fprintf(h.Stderr, "git-hooks: skipping %s: %s\n", script, reason)
+				continue
+			}
+		}
+
+		input := h.Stdin
+		if stdin != nil {
+			input = bytes.NewReader(stdin)
+		}
+		if err := execute(h, script, input); err != nil {
+			if script.Severity == SeverityWarn {
+				fmt.Fprintf(h.Stderr, "git-hooks: %s failed, not blocking as severity is warn: %v\n", script, err)
+				continue
+			}
+			return err
+		}
+	}
+	return injectTrailers(h)
+}
+
+// injectTrailers adds the trailers declared in config to the commit message
+// once the commit-msg or prepare-commit-msg scripts passed
+func injectTrailers(h Hook) error {
+	if len(h.Args) == 0 || !slices.Contains(trailers.Hooks, h.Name) {
+		return nil
+	}
+	specs, err := trailers.LoadSpecs(h.RepoDir)
+	if err != nil {
+		return err
+	}
+
+	path := h.Args[0]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(h.RepoDir, path)
+	}
+	return trailers.InjectFile(path, h.Name, h.RepoDir, specs, h.Stdout, h.Stderr)
+}
+
+func execute(h Hook, script Script, stdin io.Reader) error {
+	var cmd *exec.Cmd
+	switch {
+	case len(script.Argv) > 0:
+		cmd = exec.Command(script.Argv[0], script.Argv[1:]...)
+	case script.Command != "":
+		// Pass the hook arguments through to the command like git does
+		cmd = exec.Command("sh", append([]string{"-c", script.Command + ` "$@"`, script.Command}, h.Args...)...)
+	default:
+		cmd = exec.Command(script.Path, h.Args...)
+	}
+	cmd.Dir = h.RepoDir
+	cmd.Stdin = stdin
+	cmd.Stdout = h.Stdout
+	cmd.Stderr = h.Stderr
+	cmd.Env = append(os.Environ(), script.Env...)
+	return cmd.Run()
+}
+
+func scriptsInDir(level Level, dir string) ([]Script, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // Directory doesn't exist, which is fine
+		}
+		return nil, err
+	}
+
+	var scripts []Script
+	for _, file := range files {
+		if !file.IsDir() {
+			scripts = append(scripts, Script{Name: file.Name(), Level: level, Path: filepath.Join(dir, file.Name())})
+		}
+	}
+	return scripts, nil
+}
+
+// configCommands returns the commands from every `githooks.<hook>.command`
+// entry in system, global, local and worktree config. Commands listed in
+// `githooks.<hook>.disabled` are skipped, and a true value skips them all.
+func configCommands(h Hook) ([]Script, error) {
+	commands, err := gitconfig.GetAll(h.RepoDir, "githooks."+h.Name+".command")
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	disabled, err := gitconfig.GetAll(h.RepoDir, "githooks."+h.Name+".disabled")
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{}
+	disableAll := false
+	for _, d := range disabled {
+		// The last boolean wins, so a repository can re-enable them
+		if b, err := strconv.ParseBool(d.Value); err == nil {
+			disableAll = b
+			continue
+		}
+		skip[d.Value] = true
+	}
+	if disableAll {
+		return nil, nil
+	}
+
+	var scripts []Script
+	seen := map[string]bool{}
+	for _, c := range commands {
+		command := strings.TrimSpace(c.Value)
+		if command == "" || skip[command] || seen[command] {
+			continue
+		}
+		seen[command] = true
+		scripts = append(scripts, Script{Name: command, Level: LevelConfig, Command: command})
+	}
+	return scripts, nil
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	// Hooks that exist but are not executable are skipped silently
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0
+}
@@ -0,0 +1,513 @@
+package dispatch_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/dispatch"
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect_Order(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	systemDir := filepath.Join(home, "system")
+
+	writeScript(t, filepath.Join(systemDir, "pre-commit.d", "policy"), "exit 0")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "gitleaks"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".git-hooks", "pre-commit.d", "lint"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".husky", "pre-commit"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".git", "hooks", "pre-commit"), "exit 0")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.pre-commit.command", "make test"))
+
+	h := newHook(repo, hooksDir)
+	h.SystemDir = systemDir
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"system:policy",
+		"global:gitleaks",
+		"local:lint",
+		"config:make test",
+		"husky:pre-commit",
+		"standard:pre-commit",
+	}, names(scripts))
+}
+
+func TestCollect_ConfigCommands(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	h := newHook(repo, filepath.Join(home, ".git-hooks"))
+
+	add := func(scope gitconfig.Scope, key, value string) {
+		t.Helper()
+		cmd := exec.Command("git", "config", "--"+string(scope), "--add", key, value)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git config: %s", output)
+	}
+
+	add(gitconfig.ScopeGlobal, "githooks.pre-commit.command", "go vet ./...")
+	add(gitconfig.ScopeGlobal, "githooks.pre-commit.command", "make lint")
+	add(gitconfig.ScopeLocal, "githooks.pre-commit.command", "make lint")
+	add(gitconfig.ScopeLocal, "githooks.pre-commit.command", "make test")
+
+	t.Log("Commands from every scope run once, in config order")
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"config:go vet ./...", "config:make lint", "config:make test"}, names(scripts))
+
+	t.Log("A repository can skip a single command")
+	add(gitconfig.ScopeLocal, "githooks.pre-commit.disabled", "go vet ./...")
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"config:make lint", "config:make test"}, names(scripts))
+
+	t.Log("A true value skips all of them")
+	add(gitconfig.ScopeGlobal, "githooks.pre-commit.disabled", "true")
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Empty(t, scripts)
+
+	t.Log("A later false value re-enables them")
+	add(gitconfig.ScopeLocal, "githooks.pre-commit.disabled", "false")
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"config:make lint", "config:make test"}, names(scripts))
+}
+
+func TestRun_PassesArgs(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.commit-msg.command", "echo msg:"))
+
+	var stdout bytes.Buffer
+	h := newHook(repo, filepath.Join(home, ".git-hooks"))
+	h.Name = "commit-msg"
+	h.Args = []string{".git/COMMIT_EDITMSG"}
+	h.Stdout = &stdout
+
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.Equal(t, "msg: .git/COMMIT_EDITMSG\n", stdout.String())
+}
+
+func TestRun_StopsAtFirstFailure(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	marker := filepath.Join(home, "ran")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "fail"), "exit 1")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.pre-commit.command", "touch "+marker))
+
+	h := newHook(repo, hooksDir)
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Error(t, dispatch.Run(h, scripts))
+	require.NoFileExists(t, marker)
+}
+
+func TestRun_BranchCondition(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	marker := filepath.Join(home, "ran")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "release-check"),
+		"# git-hooks-branch: main release/*\ntouch "+marker)
+
+	var stderr bytes.Buffer
+	h := newHook(repo, hooksDir)
+	h.Stderr = &stderr
+
+	t.Log("Skipped on other branches with the reason")
+	git(t, repo, "checkout", "-q", "-b", "feature/x")
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, dispatch.Conditions{"branch": {"main", "release/*"}}, scripts[0].Conditions)
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.NoFileExists(t, marker)
+	require.Contains(t, stderr.String(), "skipping global:release-check: branch feature/x does not match main release/*")
+
+	t.Log("Runs on a matching branch")
+	git(t, repo, "checkout", "-q", "-b", "release/1.0")
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.FileExists(t, marker)
+}
+
+func TestRun_PushConditions(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	output := filepath.Join(home, "output")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-push.d", "integration"), "cat >> "+output)
+	writeScript(t, filepath.Join(hooksDir, "pre-push.d", "always"), "cat >> "+output)
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.pre-push.integration.remote", "origin"))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.pre-push.integration.ref", "main release/*"))
+
+	zero := "0000000000000000000000000000000000000000"
+	push := func(remote, ref string) string {
+		t.Helper()
+		require.NoError(t, os.RemoveAll(output))
+		h := newHook(repo, hooksDir)
+		h.Name = "pre-push"
+		h.Args = []string{remote, "git@example.com:org/repo.git"}
+		h.Stdin = bytes.NewBufferString("refs/heads/x " + zero + " " + ref + " " + zero + "\n")
+		scripts, err := dispatch.Collect(h)
+		require.NoError(t, err)
+		require.NoError(t, dispatch.Run(h, scripts))
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Log("Every script that runs receives the full input")
+	line := "refs/heads/x " + zero + " refs/heads/main " + zero + "\n"
+	require.Equal(t, line+line, push("origin", "refs/heads/main"))
+
+	t.Log("Other remotes and refs only run the unconditional script")
+	require.Equal(t, line, push("fork", "refs/heads/main"))
+	require.Equal(t, strings.Replace(line, "main", "feature", 1), push("origin", "refs/heads/feature"))
+}
+
+func TestRun_PathsCondition(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	marker := filepath.Join(home, "ran")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "go-vet"), "# git-hooks-paths: **/*.go\ntouch "+marker)
+	h := newHook(repo, hooksDir)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "README.md"), []byte("docs"), 0o644))
+	git(t, repo, "add", "README.md")
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.NoFileExists(t, marker)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "cmd"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "cmd", "main.go"), []byte("package main"), 0o644))
+	git(t, repo, "add", "cmd/main.go")
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.FileExists(t, marker)
+}
+
+func TestCollect_RepoConfig(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "gitleaks"), "# git-hooks-required: true\nexit 0")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "prettier"), "exit 0")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "shellcheck"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".git-hooks", "pre-commit.d", "lint"), "exit 0")
+
+	var stderr bytes.Buffer
+	h := newHook(repo, hooksDir)
+	h.Stderr = &stderr
+
+	t.Log("Without .git-hooks.yaml all scripts run and no chain is printed")
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"global:gitleaks", "global:prettier", "global:shellcheck", "local:lint"}, names(scripts))
+	require.True(t, scripts[0].Required)
+	require.Empty(t, stderr.String())
+
+	t.Log("A repository disables and overrides inherited scripts by name")
+	config := `hooks:
+  pre-commit:
+    disable: [gitleaks, shellcheck, missing]
+    override:
+      prettier: npx prettier --check .
+`
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte(config), 0o644))
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"global:gitleaks", "local:prettier", "local:lint"}, names(scripts))
+	require.Equal(t, "npx prettier --check .", scripts[1].Command)
+
+	output := stderr.String()
+	require.Contains(t, output, "cannot disable required script global:gitleaks")
+	require.Contains(t, output, `no inherited pre-commit script named "missing"`)
+	require.Contains(t, output, "pre-commit: global:gitleaks (required) -> local:prettier -> local:lint")
+}
+
+func TestCollect_RequiredFromConfig(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "gitleaks"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".git-hooks", "pre-commit.d", "lint"), "exit 0")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.pre-commit.gitleaks.required", "true"))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.pre-commit.lint.required", "true"))
+
+	scripts, err := dispatch.Collect(newHook(repo, hooksDir))
+	require.NoError(t, err)
+	require.True(t, scripts[0].Required)
+	require.False(t, scripts[1].Required, "repository scripts can't be required")
+}
+
+func TestCollect_RequiredConditions(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "gitleaks"), "# git-hooks-required: true\nexit 0")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "prettier"), "exit 0")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.pre-commit.gitleaks.paths", "*.go"))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.pre-commit.gitleaks.branch", "nonexistent"))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.pre-commit.prettier.branch", "main"))
+
+	var stderr bytes.Buffer
+	h := newHook(repo, hooksDir)
+	h.Stderr = &stderr
+
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, dispatch.Conditions{"paths": {"*.go"}}, scripts[0].Conditions, "the scope that required the script sets conditions")
+	require.Equal(t, dispatch.Conditions{"branch": {"main"}}, scripts[1].Conditions)
+
+	output := stderr.String()
+	require.Contains(t, output, "local config cannot change the branch condition of required script global:gitleaks")
+	require.Contains(t, output, "pre-commit: global:gitleaks (required) -> global:prettier", "the chain is shown when git config changed it")
+}
+
+func TestRun_Severity(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	marker := filepath.Join(home, "ran")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "a-policy"), "# git-hooks-severity: warn\necho violation >&2; exit 1")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "b-gitleaks"), "# git-hooks-required: true\nexit 0")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "c-marker"), "touch "+marker)
+
+	var stderr bytes.Buffer
+	h := newHook(repo, hooksDir)
+	h.Stderr = &stderr
+
+	t.Log("Failures of warn scripts don't block the hook")
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, dispatch.SeverityWarn, scripts[0].Severity)
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.FileExists(t, marker)
+	require.Contains(t, stderr.String(), "violation")
+	require.Contains(t, stderr.String(), "global:a-policy failed, not blocking as severity is warn")
+
+	t.Log("Git config overrides the header")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.pre-commit.a-policy.severity", "error"))
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Error(t, dispatch.Run(h, scripts))
+
+	t.Log("Git config can't lower the severity of a required script either")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.pre-commit.b-gitleaks.severity", "off"))
+	stderr.Reset()
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Empty(t, scripts[1].Severity)
+	require.Contains(t, stderr.String(), "local config cannot lower the severity of required script global:b-gitleaks")
+
+	t.Log("The repository overrides git config")
+	config := "hooks:\n  pre-commit:\n    severity:\n      a-policy: off\n      b-gitleaks: warn\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte(config), 0o644))
+	stderr.Reset()
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, dispatch.SeverityOff, scripts[0].Severity)
+	require.Empty(t, scripts[1].Severity, "required scripts keep their severity")
+	require.Contains(t, stderr.String(), "cannot lower the severity of required script global:b-gitleaks")
+	require.NoError(t, dispatch.Run(h, scripts))
+	require.Contains(t, stderr.String(), "skipping global:a-policy: severity is off")
+
+	t.Log("The environment overrides the repository")
+	t.Setenv(dispatch.SeverityEnvVar, "a-policy=warn")
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, dispatch.SeverityWarn, scripts[0].Severity)
+	require.NoError(t, dispatch.Run(h, scripts))
+}
+
+func TestCollect_LevelOrder(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "gitleaks"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".git-hooks", "pre-commit.d", "lint"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".husky", "pre-commit"), "exit 0")
+	writeScript(t, filepath.Join(repo, ".git", "hooks", "pre-commit"), "exit 0")
+	h := newHook(repo, hooksDir)
+
+	t.Log("Listed levels run first, the rest keep their default order")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.levelOrder", "husky"))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.disabledLevels", "standard"))
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"husky:pre-commit", "global:gitleaks", "local:lint"}, names(scripts))
+
+	t.Log("Repository config takes precedence over global config")
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "githooks.levelOrder", "local,global"))
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"local:lint", "global:gitleaks", "husky:pre-commit"}, names(scripts))
+
+	t.Log(".git-hooks.yaml takes precedence over git config and can re-enable levels")
+	config := "levels:\n  order: [standard, husky]\n  disable: []\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte(config), 0o644))
+	scripts, err = dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"standard:pre-commit", "husky:pre-commit", "global:gitleaks", "local:lint"}, names(scripts))
+}
+
+func TestCollect_DisabledLevelKeepsRequired(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "gitleaks"), "# git-hooks-required: true\nexit 0")
+	writeScript(t, filepath.Join(hooksDir, "pre-commit.d", "prettier"), "exit 0")
+	config := "levels:\n  disable: [global]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte(config), 0o644))
+
+	var stderr bytes.Buffer
+	h := newHook(repo, hooksDir)
+	h.Stderr = &stderr
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"global:gitleaks"}, names(scripts))
+	require.Contains(t, stderr.String(), "level global is disabled but global:gitleaks is required")
+}
+
+func TestCollect_HuskyV4GeneratedHook(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+
+	pkg := `{"husky": {"hooks": {"pre-commit": "lint-staged"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "package.json"), []byte(pkg), 0o644))
+	writeScript(t, filepath.Join(repo, ".git", "hooks", "pre-commit"), "# husky\n. \"$(dirname \"$0\")/husky.sh\"")
+
+	scripts, err := dispatch.Collect(newHook(repo, filepath.Join(home, ".git-hooks")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"husky:pre-commit"}, names(scripts), "the generated .git/hooks script would run the command again")
+}
+
+func TestRun_Trailers(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	msgFile := filepath.Join(repo, ".git", "COMMIT_EDITMSG")
+	require.NoError(t, os.WriteFile(msgFile, []byte("feat: add feature\n"), 0o644))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.trailer.reviewed.key", "Reviewed-on"))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "githooks.trailer.reviewed.value", "{{.Branch}}"))
+
+	h := newHook(repo, hooksDir)
+	h.Name = "commit-msg"
+	h.Args = []string{".git/COMMIT_EDITMSG"}
+
+	t.Log("A failing commit-msg script leaves the message alone")
+	writeScript(t, filepath.Join(hooksDir, "commit-msg.d", "lint"), "exit 1")
+	scripts, err := dispatch.Collect(h)
+	require.NoError(t, err)
+	require.Error(t, dispatch.Run(h, scripts))
+	content, err := os.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "feat: add feature\n", string(content))
+
+	t.Log("Trailers are added once the scripts passed")
+	writeScript(t, filepath.Join(hooksDir, "commit-msg.d", "lint"), "exit 0")
+	git(t, repo, "checkout", "-b", "feature")
+	require.NoError(t, dispatch.Run(h, scripts))
+	content, err = os.ReadFile(msgFile)
+	require.NoError(t, err)
+	require.Equal(t, "feat: add feature\n\nReviewed-on: feature\n", string(content))
+}
+
+// Helper functions
+
+// setupHome points HOME at a temp directory and isolates git from the real
+// user and system configuration
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	return home
+}
+
+// setupGitRepo creates a real git repository using git init
+func setupGitRepo(t *testing.T, dir string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	cmd := exec.Command("git", "init")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "Failed to init git repo: %s", output)
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+}
+
+// writeScript creates an executable shell script
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+}
+
+func newHook(repo, hooksDir string) dispatch.Hook {
+	return dispatch.Hook{
+		Name:     "pre-commit",
+		HooksDir: hooksDir,
+		RepoDir:  repo,
+		GitDir:   filepath.Join(repo, ".git"),
+		Stdout:   &bytes.Buffer{},
+		Stderr:   &bytes.Buffer{},
+	}
+}
+
+func names(scripts []dispatch.Script) []string {
+	var out []string
+	for _, s := range scripts {
+		out = append(out, s.String())
+	}
+	return out
+}
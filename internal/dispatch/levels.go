@@ -0,0 +1,104 @@
+package dispatch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/repoconfig"
+)
+
+const (
+	// orderKey lists levels in the order they run, e.g. `husky global local`
+	orderKey = "githooks.levelOrder"
+	// disabledKey lists levels that never run, e.g. `standard`
+	disabledKey = "githooks.disabledLevels"
+)
+
+// levelOrder returns the levels in execution order and the disabled ones.
+// .git-hooks.yaml takes precedence over git config, where the most specific
+// scope wins. Levels missing from a configured order run after the listed
+// ones, in their default order.
+func levelOrder(h Hook, cfg repoconfig.Levels) ([]Level, map[Level]bool, error) {
+	order := cfg.Order
+	if order == nil {
+		value, _, err := gitconfig.Get(h.RepoDir, gitconfig.ScopeDefault, orderKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		order = splitList(value)
+	}
+
+	disable := cfg.Disable
+	if disable == nil {
+		value, _, err := gitconfig.Get(h.RepoDir, gitconfig.ScopeDefault, disabledKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		disable = splitList(value)
+	}
+
+	var levels []Level
+	listed := map[Level]bool{}
+	for _, level := range append(parseLevels(h, order), DefaultOrder...) {
+		if !listed[level] {
+			listed[level] = true
+			levels = append(levels, level)
+		}
+	}
+
+	disabled := map[Level]bool{}
+	for _, level := range parseLevels(h, disable) {
+		disabled[level] = true
+	}
+	return levels, disabled, nil
+}
+
+func parseLevels(h Hook, names []string) []Level {
+	var levels []Level
+	for _, name := range names {
+		level := Level(strings.ToLower(name))
+		if !isLevel(level) {
+			fmt.Fprintf(h.Stderr, "git-hooks: ignoring unknown level %q\n", name)
+			continue
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+func isLevel(level Level) bool {
+	for _, l := range DefaultOrder {
+		if level == l {
+			return true
+		}
+	}
+	return false
+}
+
+// dropDisabledLevels removes the scripts of disabled levels. Required scripts
+// always run.
+func dropDisabledLevels(h Hook, scripts []Script, disabled map[Level]bool) []Script {
+	if len(disabled) == 0 {
+		return scripts
+	}
+
+	var kept []Script
+	for _, script := range scripts {
+		if disabled[script.Level] {
+			if !script.Required {
+				continue
+			}
+			fmt.Fprintf(h.Stderr, "git-hooks: level %s is disabled but %s is required\n", script.Level, script)
+		}
+		kept = append(kept, script)
+	}
+	return kept
+}
+
+// splitList splits a comma or space separated config value
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
@@ -0,0 +1,96 @@
+package dispatch
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/repoconfig"
+)
+
+// Severity decides how a script's failure is treated
+type Severity string
+
+const (
+	// SeverityError failures block the hook
+	SeverityError Severity = "error"
+	// SeverityWarn failures are reported but don't block the hook
+	SeverityWarn Severity = "warn"
+	// SeverityOff scripts don't run
+	SeverityOff Severity = "off"
+)
+
+// SeverityEnvVar overrides severities for a single invocation, either for all
+// scripts (`warn`) or by script name (`gitleaks=warn,policy=off`)
+const SeverityEnvVar = "GIT_HOOKS_SEVERITY"
+
+func parseSeverity(value string) (Severity, error) {
+	switch severity := Severity(strings.ToLower(strings.TrimSpace(value))); severity {
+	case SeverityError, SeverityWarn, SeverityOff:
+		return severity, nil
+	}
+	return "", fmt.Errorf("unknown severity %q, expected error, warn or off", value)
+}
+
+// lowers reports whether severity is less strict than current
+func (s Severity) lowers(current Severity) bool {
+	rank := map[Severity]int{SeverityOff: 0, SeverityWarn: 1, SeverityError: 2, "": 2}
+	return rank[s] < rank[current]
+}
+
+// applySeverity overrides the severity set in script headers and git config
+// with .git-hooks.yaml and then GIT_HOOKS_SEVERITY. Neither can lower the
+// severity of required scripts. It reports whether any override was set.
+func applySeverity(h Hook, scripts []Script, hookCfg repoconfig.Hook) bool {
+	names := make([]string, 0, len(hookCfg.Severity))
+	for name := range hookCfg.Severity {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	applied := false
+	for _, name := range names {
+		severity, err := parseSeverity(hookCfg.Severity[name])
+		if err != nil {
+			fmt.Fprintf(h.Stderr, "git-hooks: %s: ignoring severity of %s: %v\n", repoconfig.FileName, name, err)
+			continue
+		}
+		setSeverity(h, scripts, name, severity, repoconfig.FileName)
+		applied = true
+	}
+
+	env := os.Getenv(SeverityEnvVar)
+	for _, entry := range strings.Split(env, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, byName := strings.Cut(entry, "=")
+		if !byName {
+			name, value = "", entry
+		}
+		severity, err := parseSeverity(value)
+		if err != nil {
+			fmt.Fprintf(h.Stderr, "git-hooks: %s: %v\n", SeverityEnvVar, err)
+			continue
+		}
+		setSeverity(h, scripts, strings.TrimSpace(name), severity, SeverityEnvVar)
+		applied = true
+	}
+	return applied
+}
+
+// setSeverity sets severity on the scripts called name, or on all scripts
+// when name is empty
+func setSeverity(h Hook, scripts []Script, name string, severity Severity, source string) {
+	for i := range scripts {
+		if name != "" && scripts[i].Name != name {
+			continue
+		}
+		if scripts[i].Required && severity.lowers(scripts[i].Severity) {
+			fmt.Fprintf(h.Stderr, "git-hooks: %s cannot lower the severity of required script %s\n", source, scripts[i])
+			continue
+		}
+		scripts[i].Severity = severity
+	}
+}
@@ -0,0 +1,436 @@
+package doctor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/gitleaksconfig"
+	"github.com/rudderlabs/git-hooks/internal/provision"
+	"github.com/rudderlabs/git-hooks/internal/shim"
+)
+
+// Status is the outcome of a single check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a single check together with a suggested fix
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+	Fixed   bool   `json:"fixed,omitempty"`
+
+	// autoFix applies Fix, if it is safe to do so without asking
+	autoFix func() error
+}
+
+// Report is the list of all check results
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Failed reports whether any check failed
+func (r Report) Failed() bool {
+	for _, res := range r.Results {
+		if res.Status == StatusFail && !res.Fixed {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures which environment the checks inspect
+type Options struct {
+	// HooksDir is the directory git-hooks installs its shims into
+	HooksDir string
+	// SystemDir is the directory of a system-wide installation, if any
+	SystemDir string
+	// ScopeFile is the include file used for directory-scoped activation
+	ScopeFile string
+	// ScopeDirs are the directories git-hooks is scoped to, if any
+	ScopeDirs []string
+	// Hooks is the list of hook names a shim is expected for
+	Hooks []string
+	// Params describes the running git-hooks binary
+	Params shim.Params
+	// RepoDir is the directory checked for local overrides
+	RepoDir string
+	// Fix applies safe fixes for failing checks
+	Fix bool
+}
+
+type check func(Options) Result
+
+// Run executes every check, applying safe fixes if requested
+func Run(opts Options) Report {
+	checks := []check{
+		checkGlobalHooksPath,
+		checkOverrides,
+		checkShims,
+		checkBinaryInPath,
+		checkGitleaks,
+		checkGitleaksConfig,
+	}
+
+	var report Report
+	for _, c := range checks {
+		res := c(opts)
+		if opts.Fix && res.Status != StatusPass && res.autoFix != nil {
+			if err := res.autoFix(); err != nil {
+				res.Message += fmt.Sprintf(" (fix failed: %v)", err)
+			} else {
+				res.Fixed = true
+			}
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// WriteText renders the report for humans
+func (r Report) WriteText(w io.Writer) {
+	icons := map[Status]string{StatusPass: "✅", StatusWarn: "⚠️ ", StatusFail: "❌"}
+	for _, res := range r.Results {
+		fmt.Fprintf(w, "%s %s: %s\n", icons[res.Status], res.Name, res.Message)
+		switch {
+		case res.Fixed:
+			fmt.Fprintf(w, "   🔧 fixed: %s\n", res.Fix)
+		case res.Fix != "" && res.Status != StatusPass:
+			fmt.Fprintf(w, "   💡 %s\n", res.Fix)
+		}
+	}
+}
+
+func checkGlobalHooksPath(opts Options) Result {
+	res := Result{Name: "global core.hooksPath"}
+
+	value, ok, err := gitconfig.Get(opts.RepoDir, gitconfig.ScopeGlobal, "core.hooksPath")
+	switch {
+	case err != nil:
+		res.Status, res.Message = StatusFail, err.Error()
+	case !ok && systemInstall(opts):
+		res.Status, res.Message = StatusPass, fmt.Sprintf("not set, using the system-wide install at %s", opts.SystemDir)
+	case !ok && localInstall(opts):
+		res.Status, res.Message = StatusPass, "not set, this repository uses a local install"
+	case !ok && len(opts.ScopeDirs) > 0:
+		scopes := strings.Join(opts.ScopeDirs, ", ")
+		effective, _, _ := gitconfig.Get(opts.RepoDir, gitconfig.ScopeDefault, "core.hooksPath")
+		if samePath(effective, opts.HooksDir) {
+			res.Status, res.Message = StatusPass, fmt.Sprintf("not set, active for this repository via includeIf for %s", scopes)
+		} else {
+			res.Status, res.Message = StatusWarn, fmt.Sprintf("not set, git-hooks is only active for repositories in %s", scopes)
+			res.Fix = "git-hooks config --scope-dir <dir>"
+		}
+	case !ok:
+		res.Status, res.Message = StatusFail, "not set"
+		res.Fix = fmt.Sprintf("git config --global core.hooksPath %s", opts.HooksDir)
+		res.autoFix = func() error {
+			return gitconfig.Set(opts.RepoDir, gitconfig.ScopeGlobal, "core.hooksPath", opts.HooksDir)
+		}
+	case !samePath(value, opts.HooksDir):
+		res.Status, res.Message = StatusFail, fmt.Sprintf("set to %s instead of %s", value, opts.HooksDir)
+		res.Fix = "git-hooks config"
+	default:
+		res.Status, res.Message = StatusPass, value
+	}
+	return res
+}
+
+func checkOverrides(opts Options) Result {
+	res := Result{Name: "core.hooksPath overrides"}
+
+	entries, err := gitconfig.GetAll(opts.RepoDir, "core.hooksPath")
+	if err != nil {
+		res.Status, res.Message = StatusFail, err.Error()
+		return res
+	}
+
+	globalFile := gitconfig.GlobalFile()
+	var problems, fixes []string
+	res.Status = StatusPass
+	for _, e := range entries {
+		if e.Scope == gitconfig.ScopeGlobal && (samePath(e.Origin, globalFile) || samePath(e.Origin, opts.ScopeFile)) {
+			continue
+		}
+		if e.Scope == gitconfig.ScopeSystem && opts.SystemDir != "" && samePath(e.Value, opts.SystemDir) {
+			continue
+		}
+
+		switch e.Scope {
+		case gitconfig.ScopeLocal, gitconfig.ScopeWorktree:
+			if samePath(e.Value, opts.HooksDir) {
+				continue
+			}
+			res.Status = StatusFail
+			problems = append(problems, fmt.Sprintf("%s config sets %s", e.Scope, e.Value))
+			fixes = append(fixes, fmt.Sprintf("git config --%s --unset core.hooksPath", e.Scope))
+		default:
+			if res.Status == StatusPass {
+				res.Status = StatusWarn
+			}
+			problems = append(problems, fmt.Sprintf("%s config %s sets %s", e.Scope, e.Origin, e.Value))
+			fixes = append(fixes, fmt.Sprintf("review core.hooksPath in %s", e.Origin))
+		}
+	}
+
+	// The last entry wins, whatever the scope
+	if len(entries) > 0 && !samePath(entries[len(entries)-1].Value, shimDir(opts)) {
+		res.Status = StatusFail
+	}
+
+	if len(problems) == 0 {
+		res.Message = "none"
+		return res
+	}
+	res.Message = strings.Join(problems, "; ")
+	res.Fix = strings.Join(fixes, " && ")
+	return res
+}
+
+func checkShims(opts Options) Result {
+	res := Result{Name: "hook shims", Status: StatusPass}
+	dir := shimDir(opts)
+
+	var missing, notExecutable, foreign, stale []string
+	for _, hook := range opts.Hooks {
+		path := filepath.Join(dir, hook)
+		stat, err := os.Stat(path)
+		if err != nil {
+			missing = append(missing, hook)
+			continue
+		}
+		info, err := shim.Read(path)
+		if errors.Is(err, shim.ErrNotShim) {
+			foreign = append(foreign, hook)
+			continue
+		}
+		if err != nil {
+			missing = append(missing, hook)
+			continue
+		}
+		if stat.Mode()&0o111 == 0 {
+			notExecutable = append(notExecutable, hook)
+		}
+		if info.Stale(opts.Params) {
+			stale = append(stale, hook)
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		res.Status = StatusFail
+		problems = append(problems, "missing: "+strings.Join(missing, ", "))
+	}
+	if len(notExecutable) > 0 {
+		res.Status = StatusFail
+		problems = append(problems, "not executable: "+strings.Join(notExecutable, ", "))
+	}
+	if len(stale) > 0 {
+		if res.Status == StatusPass {
+			res.Status = StatusWarn
+		}
+		problems = append(problems, "pointing at another binary or version: "+strings.Join(stale, ", "))
+	}
+	if len(foreign) > 0 {
+		if res.Status == StatusPass {
+			res.Status = StatusWarn
+		}
+		problems = append(problems, "not managed by git-hooks: "+strings.Join(foreign, ", "))
+	}
+
+	if len(problems) == 0 {
+		res.Message = fmt.Sprintf("%d shims in %s point at %s (%s)", len(opts.Hooks), dir, opts.Params.BinaryPath, opts.Params.Version)
+		return res
+	}
+	res.Message = strings.Join(problems, "; ")
+
+	if len(missing)+len(notExecutable)+len(stale) == 0 {
+		res.Fix = fmt.Sprintf("move custom scripts into %s/<hook>.d/", dir)
+		return res
+	}
+	params := opts.Params
+	res.Fix = "git-hooks config --repair"
+	if dir != opts.HooksDir {
+		res.Fix = "sudo git-hooks config --system --repair"
+		params.Home, params.SystemDir = "", dir
+	}
+	res.autoFix = func() error {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		if _, err := shim.Repair(dir, opts.Hooks, params); err != nil {
+			return err
+		}
+		for _, hook := range notExecutable {
+			if err := os.Chmod(filepath.Join(dir, hook), 0o755); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return res
+}
+
+func checkBinaryInPath(opts Options) Result {
+	res := Result{Name: "git-hooks in PATH"}
+
+	path, err := exec.LookPath("git-hooks")
+	switch {
+	case err != nil:
+		res.Status, res.Message = StatusWarn, "not found, shims cannot fall back if the binary moves"
+		res.Fix = fmt.Sprintf("add %s to PATH", filepath.Dir(opts.Params.BinaryPath))
+	case !shim.SameBinary(path, opts.Params.BinaryPath):
+		res.Status, res.Message = StatusWarn, fmt.Sprintf("%s is a different binary than %s", path, opts.Params.BinaryPath)
+	default:
+		res.Status, res.Message = StatusPass, path
+	}
+	return res
+}
+
+func checkGitleaks(opts Options) Result {
+	res := Result{Name: "gitleaks"}
+
+	script := filepath.Join(opts.HooksDir, "pre-commit.d", "gitleaks")
+	recorded, err := readGitleaksScript(script)
+	if os.IsNotExist(err) {
+		res.Status, res.Message = StatusPass, "hook not installed"
+		return res
+	}
+	if err != nil {
+		res.Status, res.Message = StatusFail, err.Error()
+		return res
+	}
+
+	if info, err := os.Stat(recorded.path); err != nil || info.Mode()&0o111 == 0 {
+		res.Status, res.Message = StatusFail, fmt.Sprintf("%s used by the hook is missing", recorded.path)
+		res.Fix = "git-hooks add gitleaks"
+		return res
+	}
+
+	var problems []string
+	res.Status = StatusPass
+	cache := provision.CacheDir(opts.HooksDir)
+	if rel, err := filepath.Rel(cache, recorded.path); err == nil && !strings.HasPrefix(rel, "..") {
+		// Provisioned by `add gitleaks`, not meant to be in PATH
+		cachedVersion, _, _ := strings.Cut(rel, string(filepath.Separator))
+		pinned, _, _ := gitconfig.Get(opts.RepoDir, gitconfig.ScopeDefault, "githooks.gitleaks.version")
+		if pinned = strings.TrimPrefix(strings.TrimSpace(pinned), "v"); pinned != "" && pinned != cachedVersion {
+			problems = append(problems, fmt.Sprintf("githooks.gitleaks.version is %s, the hook uses %s", pinned, cachedVersion))
+		}
+	} else if inPath, err := exec.LookPath("gitleaks"); err != nil {
+		problems = append(problems, "not found in PATH")
+	} else if !shim.SameBinary(inPath, recorded.path) {
+		problems = append(problems, fmt.Sprintf("%s in PATH differs from %s used by the hook", inPath, recorded.path))
+	}
+
+	current := ""
+	if output, err := exec.Command(recorded.path, "version").Output(); err == nil {
+		current = strings.TrimSpace(string(output))
+	}
+	if recorded.version != "" && current != recorded.version {
+		problems = append(problems, fmt.Sprintf("version %s differs from %s the hook was installed with", current, recorded.version))
+	}
+
+	if len(problems) == 0 {
+		res.Message = fmt.Sprintf("%s (%s)", recorded.path, current)
+		return res
+	}
+	res.Status = StatusWarn
+	res.Message = strings.Join(problems, "; ")
+	res.Fix = "git-hooks add gitleaks"
+	return res
+}
+
+// checkGitleaksConfig shows the gitleaks config the hooks use in RepoDir
+func checkGitleaksConfig(opts Options) Result {
+	res := Result{Name: "gitleaks config", Status: StatusPass}
+	layers := gitleaksconfig.Find(opts.HooksDir, opts.RepoDir)
+	res.Message = layers.String()
+	if replaced := layers.ReplacedExtend(); replaced != "" {
+		res.Status = StatusWarn
+		res.Message += fmt.Sprintf("; the [extend] %s of %s is replaced by the global config", replaced, layers.Repo)
+		res.Fix = "drop [extend] from " + gitleaksconfig.FileName + ", it always extends " + layers.Global
+	}
+	return res
+}
+
+type gitleaksScript struct {
+	path    string
+	version string
+}
+
+// readGitleaksScript extracts the gitleaks path and version the hook script
+// was templated with
+func readGitleaksScript(path string) (gitleaksScript, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return gitleaksScript{}, err
+	}
+	defer file.Close()
+
+	var script gitleaksScript
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "GITLEAKS_PATH="):
+			script.path = strings.Trim(strings.TrimPrefix(line, "GITLEAKS_PATH="), `"`)
+		case strings.HasPrefix(line, "# gitleaks-version: "):
+			script.version = strings.TrimPrefix(line, "# gitleaks-version: ")
+		}
+	}
+	if script.path == "" {
+		return script, fmt.Errorf("no gitleaks path in %s", path)
+	}
+	return script, scanner.Err()
+}
+
+// systemInstall reports whether core.hooksPath is set system-wide to SystemDir
+func systemInstall(opts Options) bool {
+	if opts.SystemDir == "" {
+		return false
+	}
+	value, ok, err := gitconfig.Get(opts.RepoDir, gitconfig.ScopeSystem, "core.hooksPath")
+	return err == nil && ok && samePath(value, opts.SystemDir)
+}
+
+// localInstall reports whether the repository's core.hooksPath points at the
+// git-hooks shims
+func localInstall(opts Options) bool {
+	value, ok, err := gitconfig.Get(opts.RepoDir, gitconfig.ScopeLocal, "core.hooksPath")
+	return err == nil && ok && samePath(value, opts.HooksDir)
+}
+
+// shimDir returns the directory the shims are expected in
+func shimDir(opts Options) string {
+	if _, ok, _ := gitconfig.Get(opts.RepoDir, gitconfig.ScopeGlobal, "core.hooksPath"); !ok && systemInstall(opts) {
+		return opts.SystemDir
+	}
+	return opts.HooksDir
+}
+
+func samePath(a, b string) bool {
+	return filepath.Clean(expandHome(a)) == filepath.Clean(expandHome(b))
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
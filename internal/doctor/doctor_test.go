@@ -0,0 +1,229 @@
+package doctor_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/doctor"
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/shim"
+	"github.com/stretchr/testify/require"
+)
+
+var hooks = []string{"pre-commit", "commit-msg"}
+
+func TestRun_FixesMissingSetup(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+
+	opts := doctor.Options{
+		HooksDir: filepath.Join(home, ".git-hooks"),
+		Hooks:    hooks,
+		Params:   fakeBinary(t, home),
+		RepoDir:  repo,
+	}
+
+	t.Log("Reporting a missing setup")
+	report := doctor.Run(opts)
+	require.True(t, report.Failed())
+	require.Equal(t, doctor.StatusFail, result(t, report, "global core.hooksPath").Status)
+	require.Equal(t, doctor.StatusFail, result(t, report, "hook shims").Status)
+
+	t.Log("Fixing the setup")
+	opts.Fix = true
+	report = doctor.Run(opts)
+	require.False(t, report.Failed())
+	require.True(t, result(t, report, "global core.hooksPath").Fixed)
+	require.True(t, result(t, report, "hook shims").Fixed)
+
+	value, ok, err := gitconfig.Get(repo, gitconfig.ScopeGlobal, "core.hooksPath")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, opts.HooksDir, value)
+
+	t.Log("Running again reports a healthy setup")
+	opts.Fix = false
+	report = doctor.Run(opts)
+	require.False(t, report.Failed())
+	require.Equal(t, doctor.StatusPass, result(t, report, "hook shims").Status)
+}
+
+func TestRun_LocalOverride(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "core.hooksPath", hooksDir))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeLocal, "core.hooksPath", ".husky"))
+
+	report := doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: repo, Fix: true})
+
+	res := result(t, report, "core.hooksPath overrides")
+	require.Equal(t, doctor.StatusFail, res.Status)
+	require.Contains(t, res.Message, ".husky")
+	require.Equal(t, "git config --local --unset core.hooksPath", res.Fix)
+	require.False(t, res.Fixed, "local overrides are not removed automatically")
+}
+
+func TestRun_IncludedConfig(t *testing.T) {
+	home := setupHome(t)
+	repo := filepath.Join(home, "repo")
+	setupGitRepo(t, repo)
+	hooksDir := filepath.Join(home, ".git-hooks")
+
+	included := filepath.Join(home, "work.gitconfig")
+	require.NoError(t, os.WriteFile(included, []byte("[core]\n\thooksPath = /elsewhere\n"), 0o644))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "core.hooksPath", hooksDir))
+	require.NoError(t, gitconfig.Set(repo, gitconfig.ScopeGlobal, "includeIf.gitdir:"+repo+"/.path", included))
+
+	report := doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: repo})
+
+	res := result(t, report, "core.hooksPath overrides")
+	require.Equal(t, doctor.StatusFail, res.Status)
+	require.Contains(t, res.Message, included)
+}
+
+func TestRun_ScopedActivation(t *testing.T) {
+	home := setupHome(t)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	work := filepath.Join(home, "work", "repo")
+	oss := filepath.Join(home, "oss", "repo")
+	setupGitRepo(t, work)
+	setupGitRepo(t, oss)
+
+	scopeFile := filepath.Join(hooksDir, "scope.gitconfig")
+	require.NoError(t, os.MkdirAll(hooksDir, 0o755))
+	require.NoError(t, os.WriteFile(scopeFile, []byte("[core]\n\thooksPath = "+hooksDir+"\n"), 0o644))
+	scopeDir := filepath.Join(home, "work") + "/"
+	require.NoError(t, gitconfig.Set(home, gitconfig.ScopeGlobal, "includeIf.gitdir:"+scopeDir+".path", scopeFile))
+
+	opts := doctor.Options{HooksDir: hooksDir, ScopeFile: scopeFile, ScopeDirs: []string{scopeDir}, Hooks: hooks, Params: fakeBinary(t, home)}
+
+	t.Log("Repositories in scope pass")
+	opts.RepoDir = work
+	report := doctor.Run(opts)
+	require.Equal(t, doctor.StatusPass, result(t, report, "global core.hooksPath").Status)
+	require.Equal(t, doctor.StatusPass, result(t, report, "core.hooksPath overrides").Status)
+
+	t.Log("Repositories out of scope warn")
+	opts.RepoDir = oss
+	report = doctor.Run(opts)
+	require.Equal(t, doctor.StatusWarn, result(t, report, "global core.hooksPath").Status)
+}
+
+func TestRun_GitleaksVersionDrift(t *testing.T) {
+	home := setupHome(t)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	gitleaks := filepath.Join(home, "gitleaks")
+	require.NoError(t, os.WriteFile(gitleaks, []byte("#!/bin/sh\necho v8.19.0\n"), 0o755))
+
+	scriptDir := filepath.Join(hooksDir, "pre-commit.d")
+	require.NoError(t, os.MkdirAll(scriptDir, 0o755))
+	script := "#!/bin/sh\n# gitleaks-version: v8.18.0\nGITLEAKS_PATH=\"" + gitleaks + "\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(scriptDir, "gitleaks"), []byte(script), 0o755))
+
+	report := doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: home})
+
+	res := result(t, report, "gitleaks")
+	require.Equal(t, doctor.StatusWarn, res.Status)
+	require.Contains(t, res.Message, "v8.19.0 differs from v8.18.0")
+}
+
+func TestRun_GitleaksPinnedVersion(t *testing.T) {
+	home := setupHome(t)
+	hooksDir := filepath.Join(home, ".git-hooks")
+	gitleaks := filepath.Join(hooksDir, "tools", "gitleaks", "8.18.4", "gitleaks")
+	require.NoError(t, os.MkdirAll(filepath.Dir(gitleaks), 0o755))
+	require.NoError(t, os.WriteFile(gitleaks, []byte("#!/bin/sh\necho v8.18.4\n"), 0o755))
+
+	scriptDir := filepath.Join(hooksDir, "pre-commit.d")
+	require.NoError(t, os.MkdirAll(scriptDir, 0o755))
+	script := "#!/bin/sh\n# gitleaks-version: v8.18.4\nGITLEAKS_PATH=\"" + gitleaks + "\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(scriptDir, "gitleaks"), []byte(script), 0o755))
+
+	// A provisioned gitleaks doesn't need to be in PATH
+	report := doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: home})
+	res := result(t, report, "gitleaks")
+	require.Equal(t, doctor.StatusPass, res.Status, res.Message)
+
+	require.NoError(t, gitconfig.Set(home, gitconfig.ScopeGlobal, "githooks.gitleaks.version", "v8.19.0"))
+	report = doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: home})
+	res = result(t, report, "gitleaks")
+	require.Equal(t, doctor.StatusWarn, res.Status)
+	require.Contains(t, res.Message, "githooks.gitleaks.version is 8.19.0, the hook uses 8.18.4")
+}
+
+func TestRun_GitleaksConfig(t *testing.T) {
+	home := setupHome(t)
+	t.Setenv("GITLEAKS_CONFIG", "")
+	hooksDir := filepath.Join(home, ".git-hooks")
+	report := doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: home})
+	res := result(t, report, "gitleaks config")
+	require.Equal(t, doctor.StatusPass, res.Status)
+	require.Equal(t, "built-in rules", res.Message)
+
+	global := filepath.Join(hooksDir, "gitleaks", "config.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(global), 0o755))
+	require.NoError(t, os.WriteFile(global, []byte("[extend]\nuseDefault = true\n"), 0o644))
+	repoConfig := filepath.Join(home, ".gitleaks.toml")
+	require.NoError(t, os.WriteFile(repoConfig, []byte("[extend]\npath = \"base.toml\"\n"), 0o644))
+
+	report = doctor.Run(doctor.Options{HooksDir: hooksDir, Hooks: hooks, Params: fakeBinary(t, home), RepoDir: home})
+	res = result(t, report, "gitleaks config")
+	require.Equal(t, doctor.StatusWarn, res.Status)
+	require.Contains(t, res.Message, repoConfig+" extending "+global)
+	require.Contains(t, res.Message, `the [extend] path = "base.toml" of `+repoConfig+" is replaced by the global config")
+}
+
+// Helper functions
+
+// setupHome points HOME at a temp directory and isolates git from the real
+// user and system configuration
+func setupHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	return home
+}
+
+// fakeBinary creates a stand-in git-hooks binary
+func fakeBinary(t *testing.T, dir string) shim.Params {
+	t.Helper()
+
+	path := filepath.Join(dir, "bin", "git-hooks")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755))
+	return shim.Params{BinaryPath: path, Version: "v1.0.0"}
+}
+
+// setupGitRepo creates a real git repository using git init
+func setupGitRepo(t *testing.T, dir string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	cmd := exec.Command("git", "init")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "Failed to init git repo: %s", output)
+}
+
+// result returns the result of the named check
+func result(t *testing.T, report doctor.Report, name string) doctor.Result {
+	t.Helper()
+
+	for _, res := range report.Results {
+		if res.Name == name {
+			return res
+		}
+	}
+	t.Fatalf("check %q not found in report", name)
+	return doctor.Result{}
+}
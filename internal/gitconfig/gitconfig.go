@@ -0,0 +1,194 @@
+package gitconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Scope is a git config level as reported by `git config --show-scope`
+type Scope string
+
+const (
+	ScopeDefault  Scope = ""
+	ScopeSystem   Scope = "system"
+	ScopeGlobal   Scope = "global"
+	ScopeLocal    Scope = "local"
+	ScopeWorktree Scope = "worktree"
+	ScopeCommand  Scope = "command"
+)
+
+// Entry is a single value of a config key together with where it was defined
+type Entry struct {
+	Scope  Scope  `json:"scope"`
+	Origin string `json:"origin"`
+	Value  string `json:"value"`
+}
+
+// Get returns the value of key at the given scope. With ScopeDefault the
+// effective value across all scopes is returned. The boolean is false when
+// the key is not set.
+func Get(dir string, scope Scope, key string) (string, bool, error) {
+	output, err := run(dir, scopeArgs(scope, "--get", key)...)
+	if err != nil {
+		if ExitCode(err) == 1 {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("reading %s: %w", key, err)
+	}
+	return strings.TrimSuffix(string(output), "\n"), true, nil
+}
+
+// GetAll returns every value of key in all scopes, in the order git applies
+// them. The last entry is the effective one.
+func GetAll(dir, key string) ([]Entry, error) {
+	output, err := run(dir, "--show-scope", "--show-origin", "--null", "--get-all", key)
+	if err != nil {
+		if ExitCode(err) == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", key, err)
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	var entries []Entry
+	for i := 0; i+2 < len(fields); i += 3 {
+		entries = append(entries, Entry{
+			Scope:  Scope(fields[i]),
+			Origin: strings.TrimPrefix(fields[i+1], "file:"),
+			Value:  fields[i+2],
+		})
+	}
+	return entries, nil
+}
+
+// KeyValue is a config key together with one of its values and the scope
+// setting it
+type KeyValue struct {
+	Scope Scope
+	Key   string
+	Value string
+}
+
+// GetRegexp returns every key matching pattern at the given scope. Section
+// and variable names are returned lowercased, subsections as written.
+func GetRegexp(dir string, scope Scope, pattern string) ([]KeyValue, error) {
+	output, err := run(dir, scopeArgs(scope, "--show-scope", "--null", "--get-regexp", pattern)...)
+	if err != nil {
+		if ExitCode(err) == 1 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", pattern, err)
+	}
+
+	var pairs []KeyValue
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, value, _ := strings.Cut(fields[i+1], "\n")
+		pairs = append(pairs, KeyValue{Scope: Scope(fields[i]), Key: key, Value: value})
+	}
+	return pairs, nil
+}
+
+// Set writes key at the given scope
+func Set(dir string, scope Scope, key, value string) error {
+	if _, err := run(dir, scopeArgs(scope, key, value)...); err != nil {
+		return fmt.Errorf("setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// Unset removes key at the given scope. Unsetting a key that does not exist
+// is not an error.
+func Unset(dir string, scope Scope, key string) error {
+	if _, err := run(dir, scopeArgs(scope, "--unset", key)...); err != nil {
+		// Exit code 5 means the key didn't exist, which is fine
+		if ExitCode(err) == 5 {
+			return nil
+		}
+		return fmt.Errorf("unsetting %s: %w", key, err)
+	}
+	return nil
+}
+
+// RemoveSection removes a whole section, e.g. `includeIf.gitdir:~/work/`, at
+// the given scope. Removing a section that does not exist is not an error.
+func RemoveSection(dir string, scope Scope, name string) error {
+	if _, err := run(dir, scopeArgs(scope, "--remove-section", name)...); err != nil {
+		// Exit code 128 with "no such section" means it didn't exist
+		if ExitCode(err) == 128 && strings.Contains(err.Error(), "no such section") {
+			return nil
+		}
+		return fmt.Errorf("removing section %s: %w", name, err)
+	}
+	return nil
+}
+
+// GlobalFile returns the file `git config --global` writes to
+func GlobalFile() string {
+	if path := os.Getenv("GIT_CONFIG_GLOBAL"); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	gitconfig := filepath.Join(home, ".gitconfig")
+	if fileExists(gitconfig) {
+		return gitconfig
+	}
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	if xdgConfig := filepath.Join(xdg, "git", "config"); fileExists(xdgConfig) {
+		return xdgConfig
+	}
+	return gitconfig
+}
+
+func scopeArgs(scope Scope, args ...string) []string {
+	if scope == ScopeDefault {
+		return args
+	}
+	return append([]string{"--" + string(scope)}, args...)
+}
+
+func run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"config"}, args...)...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return output, &Error{err: err, stderr: strings.TrimSpace(stderr.String())}
+	}
+	return output, err
+}
+
+// Error wraps a failed git config invocation with its stderr output
+type Error struct {
+	err    error
+	stderr string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%v: %s", e.err, e.stderr) }
+
+func (e *Error) Unwrap() error { return e.err }
+
+// ExitCode returns the exit status of a failed git command, or -1 when it
+// did not run to completion
+func ExitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,241 @@
+package gitleaksconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FileName is the gitleaks config of a repository, and of the global
+	// baseline as config.toml
+	FileName = ".gitleaks.toml"
+	// IgnoreFileName lists the fingerprints of findings to ignore
+	IgnoreFileName = ".gitleaksignore"
+	// EnvVar replaces all layers with one config file
+	EnvVar = "GITLEAKS_CONFIG"
+)
+
+// GlobalDir holds the org-wide baseline: config.toml and .gitleaksignore
+func GlobalDir(hooksHome string) string {
+	return filepath.Join(hooksHome, "gitleaks")
+}
+
+// Layers are the gitleaks config and ignore files found for a repository,
+// empty when missing. The repository config extends the global one.
+type Layers struct {
+	// Env is $GITLEAKS_CONFIG, which gitleaks reads instead of the files
+	Env          string
+	Global       string
+	Repo         string
+	GlobalIgnore string
+	RepoIgnore   string
+}
+
+// Find returns the layers of repoDir. hooksHome may be empty when there is
+// no global baseline.
+func Find(hooksHome, repoDir string) Layers {
+	l := Layers{Env: os.Getenv(EnvVar)}
+	if hooksHome != "" {
+		l.Global = existing(filepath.Join(GlobalDir(hooksHome), "config.toml"))
+		l.GlobalIgnore = existing(filepath.Join(GlobalDir(hooksHome), IgnoreFileName))
+	}
+	l.Repo = existing(filepath.Join(repoDir, FileName))
+	l.RepoIgnore = existing(filepath.Join(repoDir, IgnoreFileName))
+	return l
+}
+
+func existing(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	if info, err := os.Stat(abs); err != nil || info.IsDir() {
+		return ""
+	}
+	return abs
+}
+
+// Args returns the --config and --gitleaks-ignore-path arguments of the
+// layers. Merged files are written to tmpDir, which the caller removes.
+func (l Layers) Args(tmpDir string) ([]string, error) {
+	var args []string
+	switch config, err := l.config(tmpDir); {
+	case err != nil:
+		return nil, err
+	case config != "":
+		args = append(args, "--config", config)
+	}
+	switch ignore, err := l.ignore(tmpDir); {
+	case err != nil:
+		return nil, err
+	case ignore != "":
+		args = append(args, "--gitleaks-ignore-path", ignore)
+	}
+	return args, nil
+}
+
+// config returns the config file to pass to gitleaks. $GITLEAKS_CONFIG is
+// left to gitleaks.
+func (l Layers) config(tmpDir string) (string, error) {
+	switch {
+	case l.Env != "":
+		return "", nil
+	case l.Global != "" && l.Repo != "":
+		data, err := l.Merged()
+		if err != nil {
+			return "", err
+		}
+		path := filepath.Join(tmpDir, FileName)
+		return path, os.WriteFile(path, data, 0o600)
+	case l.Repo != "":
+		return l.Repo, nil
+	default:
+		return l.Global, nil
+	}
+}
+
+// ignore returns the ignore file to pass to gitleaks, both concatenated when
+// there are two
+func (l Layers) ignore(tmpDir string) (string, error) {
+	if l.GlobalIgnore == "" || l.RepoIgnore == "" {
+		return l.GlobalIgnore + l.RepoIgnore, nil
+	}
+	var merged []byte
+	for _, path := range []string{l.GlobalIgnore, l.RepoIgnore} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		merged = append(merged, data...)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			merged = append(merged, '\n')
+		}
+	}
+	path := filepath.Join(tmpDir, IgnoreFileName)
+	return path, os.WriteFile(path, merged, 0o600)
+}
+
+// Merged returns the repository config extending the global one
+func (l Layers) Merged() ([]byte, error) {
+	data, err := os.ReadFile(l.Repo)
+	if err != nil {
+		return nil, err
+	}
+	merged, _ := Extend(data, l.Global)
+	return merged, nil
+}
+
+// ReplacedExtend returns the `[extend]` base of the repository config that
+// layering on the global config replaces, or an empty string
+func (l Layers) ReplacedExtend() string {
+	if l.Env != "" || l.Global == "" || l.Repo == "" {
+		return ""
+	}
+	data, err := os.ReadFile(l.Repo)
+	if err != nil {
+		return ""
+	}
+	_, replaced := Extend(data, l.Global)
+	return replaced
+}
+
+var (
+	tableHeader = regexp.MustCompile(`^\s*\[`)
+	extendTable = regexp.MustCompile(`^\s*\[\s*extend\s*\]\s*(#.*)?$`)
+	extendBase  = regexp.MustCompile(`^\s*(path|url|useDefault)\s*=\s*(.*?)\s*(#.*)?$`)
+)
+
+// Extend rewrites a gitleaks config to extend base through its `[extend]`
+// table. Gitleaks extends one config only, so the path, url or useDefault the
+// config had is dropped and returned as replaced; the base decides whether
+// the default rules apply. Other keys like disabledRules are kept.
+func Extend(config []byte, base string) (merged []byte, replaced string) {
+	lines := strings.Split(string(config), "\n")
+	out := make([]string, 0, len(lines)+2)
+	inExtend, found := false, false
+	for _, line := range lines {
+		switch {
+		case extendTable.MatchString(line):
+			inExtend, found = true, true
+			out = append(out, line, "path = "+strconv.Quote(base))
+			continue
+		case tableHeader.MatchString(line):
+			inExtend = false
+		case inExtend:
+			if m := extendBase.FindStringSubmatch(line); m != nil {
+				replaced = strings.TrimSpace(replaced + " " + m[1] + " = " + m[2])
+				continue
+			}
+		}
+		out = append(out, line)
+	}
+	if !found {
+		// Keys before the first table are top level, so the table goes last
+		if out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+		out = append(out, "[extend]", "path = "+strconv.Quote(base), "")
+	}
+	return []byte(strings.Join(out, "\n")), replaced
+}
+
+// Hash identifies the effective rules: `sha256:<hex>` of the config and
+// ignore files, or `default` for the built-in rules
+func (l Layers) Hash() string {
+	files := []string{l.Global, l.Repo}
+	if l.Env != "" {
+		files = []string{l.Env}
+	}
+	// The ignore files apply with $GITLEAKS_CONFIG too
+	files = append(files, l.GlobalIgnore, l.RepoIgnore)
+	hash := sha256.New()
+	hashed := false
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(hash, "%s\n%d\n", filepath.Base(path), len(data))
+		hash.Write(data)
+		hashed = true
+	}
+	if !hashed {
+		return "default"
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// String describes the effective config
+func (l Layers) String() string {
+	var config string
+	switch {
+	case l.Env != "":
+		config = l.Env + " ($" + EnvVar + ")"
+	case l.Global != "" && l.Repo != "":
+		config = l.Repo + " extending " + l.Global
+	case l.Repo != "" || l.Global != "":
+		config = l.Repo + l.Global
+	default:
+		config = "built-in rules"
+	}
+
+	var ignores []string
+	for _, path := range []string{l.GlobalIgnore, l.RepoIgnore} {
+		if path != "" {
+			ignores = append(ignores, path)
+		}
+	}
+	if len(ignores) == 0 {
+		return config
+	}
+	return config + ", ignoring findings in " + strings.Join(ignores, " and ")
+}
@@ -0,0 +1,114 @@
+package gitleaksconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/gitleaksconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtend(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		merged   string
+		replaced string
+	}{
+		{
+			name:   "no extend table",
+			config: "title = \"repo\"\n\n[[rules]]\nid = \"internal-token\"\nregex = '''itk_[0-9a-f]{32}'''\n",
+			merged: "title = \"repo\"\n\n[[rules]]\nid = \"internal-token\"\nregex = '''itk_[0-9a-f]{32}'''\n\n[extend]\npath = \"/org/config.toml\"\n",
+		},
+		{
+			name:     "extend with path and disabled rules",
+			config:   "[extend]\npath = \"../base.toml\" # team rules\ndisabledRules = [\n  \"generic-api-key\",\n]\n\n[allowlist]\npaths = ['''^vendor/''']\n",
+			merged:   "[extend]\npath = \"/org/config.toml\"\ndisabledRules = [\n  \"generic-api-key\",\n]\n\n[allowlist]\npaths = ['''^vendor/''']\n",
+			replaced: "path = \"../base.toml\"",
+		},
+		{
+			name:     "extend the defaults",
+			config:   "[ extend ]\nuseDefault = true\n",
+			merged:   "[ extend ]\npath = \"/org/config.toml\"\n",
+			replaced: "useDefault = true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, replaced := gitleaksconfig.Extend([]byte(tt.config), "/org/config.toml")
+			require.Equal(t, tt.merged, string(merged))
+			require.Equal(t, tt.replaced, replaced)
+		})
+	}
+}
+
+func TestLayers_Args(t *testing.T) {
+	t.Setenv(gitleaksconfig.EnvVar, "")
+	hooksHome, repo, tmp := t.TempDir(), t.TempDir(), t.TempDir()
+	globalDir := gitleaksconfig.GlobalDir(hooksHome)
+	require.NoError(t, os.MkdirAll(globalDir, 0o755))
+
+	// Built-in rules
+	args, err := gitleaksconfig.Find(hooksHome, repo).Args(tmp)
+	require.NoError(t, err)
+	require.Empty(t, args)
+	require.Equal(t, "default", gitleaksconfig.Find(hooksHome, repo).Hash())
+
+	// The global baseline alone
+	global := filepath.Join(globalDir, "config.toml")
+	require.NoError(t, os.WriteFile(global, []byte("[extend]\nuseDefault = true\n"), 0o644))
+	args, err = gitleaksconfig.Find(hooksHome, repo).Args(tmp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"--config", global}, args)
+	globalHash := gitleaksconfig.Find(hooksHome, repo).Hash()
+
+	// The repository config extends the baseline, ignore files are combined
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitleaks.toml"), []byte("[[rules]]\nid = \"x\"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(globalDir, ".gitleaksignore"), []byte("a:1"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitleaksignore"), []byte("b:2\n"), 0o644))
+	layers := gitleaksconfig.Find(hooksHome, repo)
+	args, err = layers.Args(tmp)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"--config", filepath.Join(tmp, ".gitleaks.toml"),
+		"--gitleaks-ignore-path", filepath.Join(tmp, ".gitleaksignore"),
+	}, args)
+	merged, err := os.ReadFile(filepath.Join(tmp, ".gitleaks.toml"))
+	require.NoError(t, err)
+	require.Equal(t, "[[rules]]\nid = \"x\"\n\n[extend]\npath = \""+global+"\"\n", string(merged))
+	ignore, err := os.ReadFile(filepath.Join(tmp, ".gitleaksignore"))
+	require.NoError(t, err)
+	require.Equal(t, "a:1\nb:2\n", string(ignore))
+	require.NotEqual(t, globalHash, layers.Hash(), "the hash covers every layer")
+	require.Equal(t, filepath.Join(repo, ".gitleaks.toml")+" extending "+global+
+		", ignoring findings in "+filepath.Join(globalDir, ".gitleaksignore")+" and "+filepath.Join(repo, ".gitleaksignore"), layers.String())
+
+	// $GITLEAKS_CONFIG replaces the layers, gitleaks reads it itself
+	t.Setenv(gitleaksconfig.EnvVar, global)
+	layers = gitleaksconfig.Find(hooksHome, repo)
+	args, err = layers.Args(tmp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"--gitleaks-ignore-path", filepath.Join(tmp, ".gitleaksignore")}, args)
+	envHash := layers.Hash()
+	require.NotEqual(t, globalHash, envHash, "the hash covers the ignore files")
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitleaksignore"), []byte("b:2\nc:3\n"), 0o644))
+	require.NotEqual(t, envHash, gitleaksconfig.Find(hooksHome, repo).Hash(), "ignored findings change the hash")
+
+	require.NoError(t, os.Remove(filepath.Join(globalDir, ".gitleaksignore")))
+	require.NoError(t, os.Remove(filepath.Join(repo, ".gitleaksignore")))
+	require.Equal(t, globalHash, gitleaksconfig.Find(hooksHome, repo).Hash())
+}
+
+func TestLayers_ReplacedExtend(t *testing.T) {
+	t.Setenv(gitleaksconfig.EnvVar, "")
+	hooksHome, repo := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".gitleaks.toml"), []byte("[extend]\nuseDefault = true\n"), 0o644))
+	require.Empty(t, gitleaksconfig.Find(hooksHome, repo).ReplacedExtend(), "nothing is replaced without a global config")
+
+	require.NoError(t, os.MkdirAll(gitleaksconfig.GlobalDir(hooksHome), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(gitleaksconfig.GlobalDir(hooksHome), "config.toml"), nil, 0o644))
+	require.Equal(t, "useDefault = true", gitleaksconfig.Find(hooksHome, repo).ReplacedExtend())
+}
@@ -0,0 +1,47 @@
+package glob
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Match matches value against a glob where `*` stays within a path segment
+// and `**` matches across segments
+func Match(pattern, value string) bool {
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// `**/` also matches no directory at all
+					i++
+					re.WriteString("(.*/)?")
+				} else {
+					re.WriteString(".*")
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	matched, err := regexp.MatchString(re.String(), value)
+	return err == nil && matched
+}
+
+// MatchAny reports whether value matches any of patterns
+func MatchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
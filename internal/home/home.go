@@ -0,0 +1,42 @@
+package home
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// EnvVar overrides the git-hooks home directory
+const EnvVar = "GIT_HOOKS_HOME"
+
+var ErrNoHome = errors.New("cannot determine git-hooks home directory")
+
+// Resolve returns the absolute git-hooks home directory. An explicit dir wins,
+// followed by $GIT_HOOKS_HOME, an existing ~/.git-hooks,
+// $XDG_CONFIG_HOME/git-hooks and finally ~/.git-hooks.
+func Resolve(dir string) (string, error) {
+	if dir == "" {
+		dir = os.Getenv(EnvVar)
+	}
+	if dir != "" {
+		return filepath.Abs(dir)
+	}
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w, set $%s or --home", ErrNoHome, err, EnvVar)
+	}
+
+	// Keep using an existing setup even if XDG_CONFIG_HOME was set later
+	legacy := filepath.Join(userHome, ".git-hooks")
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy, nil
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "git-hooks"), nil
+	}
+
+	return legacy, nil
+}
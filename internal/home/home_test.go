@@ -0,0 +1,63 @@
+package home_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/home"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve_ExplicitDir(t *testing.T) {
+	t.Setenv(home.EnvVar, "/from/env")
+
+	dir, err := home.Resolve("/from/flag")
+	require.NoError(t, err)
+	require.Equal(t, "/from/flag", dir)
+}
+
+func TestResolve_EnvVar(t *testing.T) {
+	t.Setenv(home.EnvVar, "/from/env")
+
+	dir, err := home.Resolve("")
+	require.NoError(t, err)
+	require.Equal(t, "/from/env", dir)
+}
+
+func TestResolve_XDGConfigHome(t *testing.T) {
+	userHome := t.TempDir()
+	t.Setenv("HOME", userHome)
+	t.Setenv(home.EnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(userHome, ".config"))
+
+	dir, err := home.Resolve("")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(userHome, ".config", "git-hooks"), dir)
+
+	t.Log("An existing ~/.git-hooks takes precedence over XDG_CONFIG_HOME")
+	require.NoError(t, os.Mkdir(filepath.Join(userHome, ".git-hooks"), 0o755))
+
+	dir, err = home.Resolve("")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(userHome, ".git-hooks"), dir)
+}
+
+func TestResolve_DefaultsToUserHome(t *testing.T) {
+	userHome := t.TempDir()
+	t.Setenv("HOME", userHome)
+	t.Setenv(home.EnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	dir, err := home.Resolve("")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(userHome, ".git-hooks"), dir)
+}
+
+func TestResolve_NoHome(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv(home.EnvVar, "")
+
+	_, err := home.Resolve("")
+	require.ErrorIs(t, err, home.ErrNoHome)
+}
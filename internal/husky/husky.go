@@ -0,0 +1,217 @@
+package husky
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layout is the way a Husky version wires up hooks
+type Layout int
+
+const (
+	// LayoutNone means the repository doesn't use Husky
+	LayoutNone Layout = iota
+	// LayoutV4 keeps commands in package.json or .huskyrc and installs
+	// generated scripts in .git/hooks that run them
+	LayoutV4
+	// LayoutV8 keeps user scripts in .husky/<hook>, which source
+	// .husky/_/husky.sh (Husky v5 to v8)
+	LayoutV8
+	// LayoutV9 keeps user scripts in .husky/<hook>, run by generated
+	// wrappers in .husky/_/<hook> that source .husky/_/h
+	LayoutV9
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutV4:
+		return "husky v4"
+	case LayoutV8:
+		return "husky v5-v8"
+	case LayoutV9:
+		return "husky v9"
+	}
+	return "none"
+}
+
+// Invocation is how to run a Husky hook
+type Invocation struct {
+	// Argv is the full command line, including the hook arguments
+	Argv []string
+	// Env is added to the environment
+	Env []string
+}
+
+// Detect returns the Husky layout of the repository at repoDir
+func Detect(repoDir string) (Layout, error) {
+	dir := filepath.Join(repoDir, ".husky")
+	if isDir(dir) {
+		switch {
+		case exists(filepath.Join(dir, "_", "h")):
+			return LayoutV9, nil
+		case exists(filepath.Join(dir, "_", "husky.sh")):
+			return LayoutV8, nil
+		}
+
+		// .husky/_ is generated on install and usually not committed, so
+		// tell the layouts apart by what the user scripts source
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return LayoutNone, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err == nil && strings.Contains(string(content), "husky.sh") {
+				return LayoutV8, nil
+			}
+		}
+		return LayoutV9, nil
+	}
+
+	_, ok, err := v4Hooks(repoDir)
+	if err != nil {
+		return LayoutNone, err
+	}
+	if ok {
+		return LayoutV4, nil
+	}
+	return LayoutNone, nil
+}
+
+// Resolve returns how to run the Husky hook in the repository at repoDir, and
+// false when Husky has no such hook. Each hook resolves to exactly one
+// invocation with the environment its Husky version expects.
+func Resolve(repoDir, hook string, args []string) (Invocation, bool, error) {
+	layout, err := Detect(repoDir)
+	if err != nil {
+		return Invocation{}, false, err
+	}
+
+	switch layout {
+	case LayoutV9:
+		// The wrapper handles HUSKY=0, the init script and PATH and then
+		// runs the user script, so running both would run it twice
+		wrapper := filepath.Join(repoDir, ".husky", "_", hook)
+		if isExecutable(wrapper) {
+			return Invocation{Argv: append([]string{wrapper}, args...)}, true, nil
+		}
+		// Not installed yet, run the user script the way the wrapper would
+		script := filepath.Join(repoDir, ".husky", hook)
+		if !exists(script) || os.Getenv("HUSKY") == "0" {
+			return Invocation{}, false, nil
+		}
+		return Invocation{
+			Argv: append([]string{"sh", "-e", script}, args...),
+			Env:  []string{"PATH=" + filepath.Join(repoDir, "node_modules", ".bin") + string(os.PathListSeparator) + os.Getenv("PATH")},
+		}, true, nil
+
+	case LayoutV8:
+		// The user script sources husky.sh itself. Git only runs
+		// executable hooks, so neither do we.
+		script := filepath.Join(repoDir, ".husky", hook)
+		if !isExecutable(script) {
+			return Invocation{}, false, nil
+		}
+		return Invocation{Argv: append([]string{script}, args...)}, true, nil
+
+	case LayoutV4:
+		if os.Getenv("HUSKY_SKIP_HOOKS") == "1" || os.Getenv("HUSKY") == "0" {
+			return Invocation{}, false, nil
+		}
+		hooks, _, err := v4Hooks(repoDir)
+		if err != nil {
+			return Invocation{}, false, err
+		}
+		command := hooks[hook]
+		if command == "" {
+			return Invocation{}, false, nil
+		}
+		// Husky v4 passes the git arguments in HUSKY_GIT_PARAMS, not as
+		// arguments, e.g. `commitlint -E HUSKY_GIT_PARAMS`
+		return Invocation{
+			Argv: []string{"sh", "-c", command},
+			Env:  []string{"HUSKY_GIT_PARAMS=" + strings.Join(args, " ")},
+		}, true, nil
+	}
+	return Invocation{}, false, nil
+}
+
+// IsGenerated reports whether the hook script at path was installed into
+// .git/hooks by Husky v4. Those scripts run the package.json commands, which
+// Resolve already covers.
+func IsGenerated(path string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	// Husky v4 and older mark the second line
+	lines := strings.SplitN(string(content), "\n", 3)
+	return len(lines) > 1 && strings.TrimSpace(lines[1]) == "# husky"
+}
+
+// v4Hooks returns the hook commands from the Husky v4 configuration, and
+// false when there is none. JavaScript configuration files can't be read.
+func v4Hooks(repoDir string) (map[string]string, bool, error) {
+	type config struct {
+		Hooks map[string]string `json:"hooks" yaml:"hooks"`
+	}
+
+	for _, name := range []string{".huskyrc", ".huskyrc.json", ".huskyrc.yaml", ".huskyrc.yml"} {
+		data, err := os.ReadFile(filepath.Join(repoDir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		// YAML is a superset of the JSON Husky accepts
+		var cfg config
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, false, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		return cfg.Hooks, true, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(repoDir, "package.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var pkg struct {
+		Husky json.RawMessage `json:"husky"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil { //nolint:forbidigo
+		return nil, false, fmt.Errorf("parsing package.json: %w", err)
+	}
+	var cfg config
+	if len(pkg.Husky) == 0 || json.Unmarshal(pkg.Husky, &cfg) != nil { //nolint:forbidigo
+		return nil, false, nil // No Husky v4 configuration
+	}
+	return cfg.Hooks, true, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0
+}
@@ -0,0 +1,166 @@
+package husky_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/husky"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve_V9(t *testing.T) {
+	repo := t.TempDir()
+	log := filepath.Join(repo, "log")
+
+	// Layout written by `husky` v9: the user script isn't executable and the
+	// generated wrapper runs it through .husky/_/h
+	writeFile(t, filepath.Join(repo, ".husky", "pre-commit"), "echo user \"$@\" >> "+log+"\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "_", "h"), `#!/usr/bin/env sh
+[ "$HUSKY" = "0" ] && exit 0
+n=$(basename "$0")
+s=$(dirname "$(dirname "$0")")/$n
+[ ! -f "$s" ] && exit 0
+echo wrapper >> `+log+`
+sh -e "$s" "$@"
+`, 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "_", "pre-commit"), "#!/usr/bin/env sh\n. \"$(dirname \"$0\")/h\"\n", 0o755)
+
+	layout, err := husky.Detect(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutV9, layout)
+
+	t.Log("The wrapper runs the user script exactly once")
+	run(t, repo, "pre-commit", "a")
+	require.Equal(t, "wrapper\nuser a\n", readFile(t, log))
+
+	t.Log("Without the generated wrappers the user script runs directly")
+	require.NoError(t, os.Remove(filepath.Join(repo, ".husky", "_", "pre-commit")))
+	require.NoError(t, os.Remove(log))
+	run(t, repo, "pre-commit", "b")
+	require.Equal(t, "user b\n", readFile(t, log))
+
+	t.Log("HUSKY=0 disables hooks")
+	t.Setenv("HUSKY", "0")
+	_, ok, err := husky.Resolve(repo, "pre-commit", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestResolve_V8(t *testing.T) {
+	repo := t.TempDir()
+	log := filepath.Join(repo, "log")
+
+	writeFile(t, filepath.Join(repo, ".husky", "_", "husky.sh"), "echo husky.sh >> "+log+"\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "pre-commit"), `#!/usr/bin/env sh
+. "$(dirname -- "$0")/_/husky.sh"
+echo user "$@" >> `+log+"\n", 0o755)
+	writeFile(t, filepath.Join(repo, ".husky", "commit-msg"), "#!/bin/sh\n. \"$(dirname -- \"$0\")/_/husky.sh\"\n", 0o644)
+
+	layout, err := husky.Detect(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutV8, layout)
+
+	run(t, repo, "pre-commit", "a")
+	require.Equal(t, "husky.sh\nuser a\n", readFile(t, log))
+
+	t.Log("Non-executable hooks don't run, like with git")
+	_, ok, err := husky.Resolve(repo, "commit-msg", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	t.Log("The layout is detected before husky.sh is installed")
+	require.NoError(t, os.RemoveAll(filepath.Join(repo, ".husky", "_")))
+	layout, err = husky.Detect(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutV8, layout)
+}
+
+func TestResolve_V4(t *testing.T) {
+	repo := t.TempDir()
+	log := filepath.Join(repo, "log")
+
+	writeFile(t, filepath.Join(repo, "package.json"), `{
+  "name": "app",
+  "husky": {
+    "hooks": {
+      "commit-msg": "echo \"$HUSKY_GIT_PARAMS\" >> `+log+`"
+    }
+  }
+}`, 0o644)
+
+	layout, err := husky.Detect(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutV4, layout)
+
+	t.Log("Git arguments are passed in HUSKY_GIT_PARAMS")
+	run(t, repo, "commit-msg", ".git/COMMIT_EDITMSG")
+	require.Equal(t, ".git/COMMIT_EDITMSG\n", readFile(t, log))
+
+	t.Log("Hooks without a command don't run")
+	_, ok, err := husky.Resolve(repo, "pre-commit", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	t.Log(".huskyrc takes precedence over package.json")
+	writeFile(t, filepath.Join(repo, ".huskyrc"), `{"hooks": {"pre-commit": "true"}}`, 0o644)
+	invocation, ok, err := husky.Resolve(repo, "pre-commit", nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"sh", "-c", "true"}, invocation.Argv)
+}
+
+func TestDetect_None(t *testing.T) {
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, "package.json"), `{"name": "app", "devDependencies": {"husky": "^9.0.0"}}`, 0o644)
+
+	layout, err := husky.Detect(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutNone, layout)
+}
+
+func TestIsGenerated(t *testing.T) {
+	dir := t.TempDir()
+
+	v4 := filepath.Join(dir, "v4")
+	writeFile(t, v4, "#!/bin/sh\n# husky\n\n# Created by Husky v4.3.8\n. \"$(dirname \"$0\")/husky.sh\"\n", 0o755)
+	require.True(t, husky.IsGenerated(v4))
+
+	custom := filepath.Join(dir, "custom")
+	writeFile(t, custom, "#!/bin/sh\nexit 0\n", 0o755)
+	require.False(t, husky.IsGenerated(custom))
+}
+
+// Helper functions
+
+// run resolves and executes the hook from the repository root
+func run(t *testing.T, repo, hook string, args ...string) {
+	t.Helper()
+
+	invocation, ok, err := husky.Resolve(repo, hook, args)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	cmd := exec.Command(invocation.Argv[0], invocation.Argv[1:]...)
+	cmd.Dir = repo
+	cmd.Env = append(os.Environ(), invocation.Env...)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "running %s: %s", strings.Join(invocation.Argv, " "), output)
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
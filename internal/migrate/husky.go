@@ -0,0 +1,326 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	cleangit "github.com/rudderlabs/git-hooks/internal/clean-local-git"
+	"github.com/rudderlabs/git-hooks/internal/husky"
+	"github.com/rudderlabs/git-hooks/internal/shim"
+)
+
+// ScriptName is the name migrated Husky scripts get in `.git-hooks/<hook>.d`
+const ScriptName = "husky"
+
+// ErrNoHusky is returned for repositories without a .husky directory
+var ErrNoHusky = errors.New("no Husky hooks found")
+
+var (
+	// huskyLine matches the boilerplate Husky v5-v9 put in user scripts
+	huskyLine = regexp.MustCompile(`^\s*\.\s+"?\$\(dirname\s+(--\s+)?"?\$0"?\)/_/husky\.sh"?\s*$`)
+	// prepareLine matches the `prepare` entry of package.json scripts
+	prepareLine = regexp.MustCompile(`^(\s*)"prepare"\s*:\s*"((?:[^"\\]|\\.)*)"\s*(,?)\s*$`)
+	// huskyCommand matches the parts of a prepare script that install Husky
+	huskyCommand = regexp.MustCompile(`^(npx\s+)?husky(\s+install)?(\s+\S+)?$`)
+)
+
+// Move is a Husky script moved into the local git-hooks directory
+type Move struct {
+	Hook       string
+	From, To   string
+	OldContent string
+	NewContent string
+	// Disabled is set for scripts git skipped as they weren't executable,
+	// they keep not running with severity off
+	Disabled bool
+}
+
+// HuskyPlan lists the changes that migrate a repository from Husky to
+// git-hooks
+type HuskyPlan struct {
+	Repo   string
+	Layout husky.Layout
+	Moves  []Move
+	// Prepare is the package.json content without the Husky prepare script,
+	// empty when there is nothing to remove
+	Prepare    string
+	oldPackage string
+	// Repository is set when a local core.hooksPath has to be unset
+	Repository *cleangit.Repository
+}
+
+// PlanHusky returns the changes that migrate the repository at repoDir
+func PlanHusky(repoDir string) (HuskyPlan, error) {
+	repo, err := cleangit.Open(repoDir)
+	if err != nil {
+		return HuskyPlan{}, err
+	}
+	plan := HuskyPlan{Repo: repo.Path}
+
+	if plan.Layout, err = husky.Detect(repo.Path); err != nil {
+		return plan, err
+	}
+	switch plan.Layout {
+	case husky.LayoutNone:
+		return plan, ErrNoHusky
+	case husky.LayoutV4:
+		return plan, fmt.Errorf("husky v4 configuration in package.json is not supported, upgrade to a newer Husky first")
+	}
+
+	entries, err := os.ReadDir(filepath.Join(repo.Path, ".husky"))
+	if err != nil {
+		return plan, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !slices.Contains(shim.Hooks, entry.Name()) {
+			continue
+		}
+		move, err := planMove(repo.Path, entry.Name(), plan.Layout)
+		if err != nil {
+			return plan, err
+		}
+		plan.Moves = append(plan.Moves, move)
+	}
+
+	if plan.Prepare, plan.oldPackage, err = planPrepare(repo.Path); err != nil {
+		return plan, err
+	}
+
+	if repo.HasCustomHooks && !repo.Managed {
+		plan.Repository = &repo
+	}
+	return plan, nil
+}
+
+func planMove(repoDir, hook string, layout husky.Layout) (Move, error) {
+	from := filepath.Join(".husky", hook)
+	to := filepath.Join(".git-hooks", hook+".d", ScriptName)
+
+	if _, err := os.Stat(filepath.Join(repoDir, to)); err == nil {
+		return Move{}, fmt.Errorf("%s already exists", to)
+	}
+	content, err := os.ReadFile(filepath.Join(repoDir, from))
+	if err != nil {
+		return Move{}, err
+	}
+	info, err := os.Stat(filepath.Join(repoDir, from))
+	if err != nil {
+		return Move{}, err
+	}
+	// Husky v9 runs scripts with sh, before that git ran them itself
+	disabled := layout != husky.LayoutV9 && info.Mode()&0o111 == 0
+
+	var body []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if !huskyLine.MatchString(line) {
+			body = append(body, line)
+		}
+	}
+
+	// Husky v9 runs scripts with sh, git-hooks executes them
+	lines := []string{"#!/bin/sh"}
+	if len(body) > 0 && strings.HasPrefix(body[0], "#!") {
+		lines, body = []string{body[0]}, body[1:]
+	}
+	if disabled {
+		lines = append(lines, "# git-hooks-severity: off")
+	}
+	if layout == husky.LayoutV9 {
+		// Husky v9 puts package binaries on the PATH
+		lines = append(lines, `export PATH="node_modules/.bin:$PATH"`)
+	}
+	lines = append(lines, body...)
+
+	return Move{
+		Hook:       hook,
+		From:       from,
+		To:         to,
+		OldContent: string(content),
+		NewContent: strings.Join(lines, "\n") + "\n",
+		Disabled:   disabled,
+	}, nil
+}
+
+// planPrepare returns package.json without the Husky install step of the
+// prepare script. The file is edited as text to keep its formatting.
+func planPrepare(repoDir string) (string, string, error) {
+	data, err := os.ReadFile(filepath.Join(repoDir, "package.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		m := prepareLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent, script, comma := m[1], m[2], m[3]
+
+		var kept []string
+		for _, part := range strings.Split(script, "&&") {
+			if !huskyCommand.MatchString(strings.TrimSpace(part)) {
+				kept = append(kept, strings.TrimSpace(part))
+			}
+		}
+		if len(kept) == len(strings.Split(script, "&&")) {
+			return "", "", nil // Husky isn't installed by the prepare script
+		}
+
+		if len(kept) > 0 {
+			lines[i] = fmt.Sprintf(`%s"prepare": "%s"%s`, indent, strings.Join(kept, " && "), comma)
+		} else {
+			lines = append(lines[:i], lines[i+1:]...)
+			if comma == "" && i > 0 {
+				// It was the last entry, drop the comma of the one before
+				lines[i-1] = strings.TrimSuffix(strings.TrimRight(lines[i-1], " \t"), ",")
+			}
+		}
+		return strings.Join(lines, "\n"), string(data), nil
+	}
+	return "", "", nil
+}
+
+// Empty reports whether there is nothing to migrate
+func (p HuskyPlan) Empty() bool {
+	return len(p.Moves) == 0 && p.Prepare == "" && p.Repository == nil
+}
+
+// Apply makes the planned changes. The package.json prepare script is only
+// changed when removePrepare is set.
+func (p HuskyPlan) Apply(ctx context.Context, removePrepare bool) error {
+	for _, move := range p.Moves {
+		to := filepath.Join(p.Repo, move.To)
+		if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(to, []byte(move.NewContent), 0o755); err != nil {
+			return fmt.Errorf("writing %s: %w", move.To, err)
+		}
+		if err := os.Remove(filepath.Join(p.Repo, move.From)); err != nil {
+			return fmt.Errorf("removing %s: %w", move.From, err)
+		}
+	}
+
+	// Drop the generated Husky files and the directory when nothing else
+	// is left in it
+	huskyDir := filepath.Join(p.Repo, ".husky")
+	if err := os.RemoveAll(filepath.Join(huskyDir, "_")); err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(huskyDir); err == nil && len(entries) == 0 {
+		if err := os.Remove(huskyDir); err != nil {
+			return err
+		}
+	}
+
+	if removePrepare && p.Prepare != "" {
+		if err := os.WriteFile(filepath.Join(p.Repo, "package.json"), []byte(p.Prepare), 0o644); err != nil {
+			return fmt.Errorf("writing package.json: %w", err)
+		}
+	}
+
+	if p.Repository != nil {
+		summary := cleangit.Clean(ctx, []cleangit.Repository{*p.Repository})
+		for _, result := range summary.Results {
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+	return nil
+}
+
+// WriteSummary prints the planned changes as a diff
+func (p HuskyPlan) WriteSummary(w io.Writer) {
+	fmt.Fprintf(w, "Migrating %s hooks in %s\n", p.Layout, p.Repo)
+
+	for _, move := range p.Moves {
+		fmt.Fprintf(w, "\nrename %s => %s\n", move.From, move.To)
+		if move.Disabled {
+			fmt.Fprintf(w, "%s is not executable and never ran, it keeps not running with severity off\n", move.From)
+		}
+		writeDiff(w, move.From, move.To, move.OldContent, move.NewContent)
+	}
+
+	if p.Prepare != "" {
+		fmt.Fprintln(w)
+		writeDiff(w, "package.json", "package.json", p.oldPackage, p.Prepare)
+	}
+
+	if p.Repository != nil {
+		fmt.Fprintf(w, "\nunset core.hooksPath = %s in %s\n", p.Repository.CustomHooksPath, p.Repository.ConfigPath)
+	}
+}
+
+// writeDiff prints the changed lines of old and new with a line of context
+func writeDiff(w io.Writer, from, to, oldContent, newContent string) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+
+	a := strings.Split(strings.TrimSuffix(oldContent, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(newContent, "\n"), "\n")
+	ops := diffLines(a, b)
+
+	changed := make([]bool, len(ops))
+	for i, op := range ops {
+		changed[i] = op.kind != ' '
+	}
+	for i, op := range ops {
+		near := changed[i] || i > 0 && changed[i-1] || i+1 < len(ops) && changed[i+1]
+		if near {
+			fmt.Fprintf(w, "%c%s\n", op.kind, op.line)
+		}
+	}
+}
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines returns the edit script from a to b based on their longest
+// common subsequence, which is fine for files of a few hundred lines
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			// Deletions first, like diff -u
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
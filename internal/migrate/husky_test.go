@@ -0,0 +1,157 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/husky"
+	"github.com/rudderlabs/git-hooks/internal/migrate"
+	"github.com/stretchr/testify/require"
+)
+
+const packageJSON = `{
+  "name": "app",
+  "scripts": {
+    "test": "jest",
+    "prepare": "husky install"
+  }
+}
+`
+
+func TestPlanHusky_V8(t *testing.T) {
+	repo := setupGitRepo(t)
+	writeFile(t, filepath.Join(repo, "package.json"), packageJSON, 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "_", "husky.sh"), "#!/usr/bin/env sh\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "_", ".gitignore"), "*\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "pre-commit"), `#!/usr/bin/env sh
+. "$(dirname -- "$0")/_/husky.sh"
+
+npx lint-staged
+`, 0o755)
+	writeFile(t, filepath.Join(repo, ".husky", "pre-push"), "#!/usr/bin/env sh\nnpm test\n", 0o644)
+	git(t, repo, "config", "core.hooksPath", ".husky")
+
+	plan, err := migrate.PlanHusky(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutV8, plan.Layout)
+	require.Len(t, plan.Moves, 2)
+	require.Equal(t, "#!/usr/bin/env sh\n\nnpx lint-staged\n", plan.Moves[0].NewContent)
+	require.False(t, plan.Moves[0].Disabled)
+	require.Equal(t, "#!/usr/bin/env sh\n# git-hooks-severity: off\nnpm test\n", plan.Moves[1].NewContent, "git skipped the script as it isn't executable")
+	require.True(t, plan.Moves[1].Disabled)
+	require.NotNil(t, plan.Repository)
+
+	var summary bytes.Buffer
+	plan.WriteSummary(&summary)
+	require.Contains(t, summary.String(), "rename .husky/pre-commit => .git-hooks/pre-commit.d/husky")
+	require.Contains(t, summary.String(), "-. \"$(dirname -- \"$0\")/_/husky.sh\"")
+	require.Contains(t, summary.String(), "-    \"test\": \"jest\",\n-    \"prepare\": \"husky install\"\n+    \"test\": \"jest\"\n")
+	require.Contains(t, summary.String(), "unset core.hooksPath = .husky")
+	require.Contains(t, summary.String(), ".husky/pre-push is not executable and never ran, it keeps not running with severity off")
+
+	t.Log("Applying moves the scripts and unsets core.hooksPath")
+	require.NoError(t, plan.Apply(context.Background(), true))
+	require.NoDirExists(t, filepath.Join(repo, ".husky"))
+	info, err := os.Stat(filepath.Join(repo, ".git-hooks", "pre-commit.d", "husky"))
+	require.NoError(t, err)
+	require.NotZero(t, info.Mode()&0o111)
+
+	pkg := readFile(t, filepath.Join(repo, "package.json"))
+	require.NotContains(t, pkg, "prepare")
+	require.Contains(t, pkg, `"test": "jest"`+"\n")
+
+	_, err = exec.Command("git", "-C", repo, "config", "--local", "core.hooksPath").Output()
+	require.Error(t, err, "core.hooksPath should be unset")
+
+	t.Log("Running again finds nothing to migrate")
+	_, err = migrate.PlanHusky(repo)
+	require.ErrorIs(t, err, migrate.ErrNoHusky)
+}
+
+func TestPlanHusky_V9(t *testing.T) {
+	repo := setupGitRepo(t)
+	writeFile(t, filepath.Join(repo, ".husky", "pre-commit"), "npm test\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "commit-msg"), "npx commitlint --edit \"$1\"\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".husky", "common.sh"), "helper\n", 0o644)
+
+	plan, err := migrate.PlanHusky(repo)
+	require.NoError(t, err)
+	require.Equal(t, husky.LayoutV9, plan.Layout)
+	require.Len(t, plan.Moves, 2, "only git hooks are moved")
+	require.Nil(t, plan.Repository)
+	require.Empty(t, plan.Prepare)
+
+	var moved []string
+	for _, move := range plan.Moves {
+		moved = append(moved, move.Hook)
+	}
+	require.ElementsMatch(t, []string{"pre-commit", "commit-msg"}, moved)
+
+	for _, move := range plan.Moves {
+		if move.Hook == "pre-commit" {
+			require.Equal(t, "#!/bin/sh\nexport PATH=\"node_modules/.bin:$PATH\"\nnpm test\n", move.NewContent)
+		}
+	}
+
+	require.NoError(t, plan.Apply(context.Background(), false))
+	require.FileExists(t, filepath.Join(repo, ".husky", "common.sh"), "other files stay")
+	require.NoFileExists(t, filepath.Join(repo, ".husky", "pre-commit"))
+}
+
+func TestPlanHusky_PrepareWithOtherCommands(t *testing.T) {
+	repo := setupGitRepo(t)
+	writeFile(t, filepath.Join(repo, ".husky", "pre-commit"), "npm test\n", 0o644)
+	writeFile(t, filepath.Join(repo, "package.json"), strings.Replace(packageJSON, `"husky install"`, `"husky && npm run build"`, 1), 0o644)
+
+	plan, err := migrate.PlanHusky(repo)
+	require.NoError(t, err)
+	require.Contains(t, plan.Prepare, `    "prepare": "npm run build"`+"\n")
+}
+
+func TestPlanHusky_ExistingTarget(t *testing.T) {
+	repo := setupGitRepo(t)
+	writeFile(t, filepath.Join(repo, ".husky", "pre-commit"), "npm test\n", 0o644)
+	writeFile(t, filepath.Join(repo, ".git-hooks", "pre-commit.d", "husky"), "#!/bin/sh\n", 0o755)
+
+	_, err := migrate.PlanHusky(repo)
+	require.ErrorContains(t, err, "already exists")
+}
+
+// Helper functions
+
+func setupGitRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	git(t, dir, "init")
+	return dir
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), mode))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
@@ -0,0 +1,9 @@
+package plural
+
+// Of returns singular when n is one and plural otherwise
+func Of(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,69 @@
+package prepush
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// zeroSHA is what git reports for a ref that doesn't exist on one side of a push
+var zeroSHA = regexp.MustCompile(`^0+$`)
+
+// Update is one line of pre-push input
+type Update struct {
+	LocalRef, LocalSHA, RemoteRef, RemoteSHA string
+}
+
+// Parse reads the ref updates git passes to pre-push on stdin
+func Parse(stdin []byte) []Update {
+	var updates []Update
+	for _, line := range strings.Split(string(stdin), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 4 {
+			updates = append(updates, Update{fields[0], fields[1], fields[2], fields[3]})
+		}
+	}
+	return updates
+}
+
+// Deleted reports whether the update deletes the remote ref
+func (u Update) Deleted() bool {
+	return zeroSHA.MatchString(u.LocalSHA)
+}
+
+// Revisions returns the git log arguments selecting the pushed commits. New
+// refs, and remote commits missing locally, exclude everything already on
+// remote instead. Pushes to a URL have no remote-tracking refs of their own,
+// so they exclude what is on any remote.
+func (u Update) Revisions(repoDir, remote string) []string {
+	args := []string{u.LocalSHA, "--not"}
+	if !zeroSHA.MatchString(u.RemoteSHA) && hasCommit(repoDir, u.RemoteSHA) {
+		return append(args, u.RemoteSHA)
+	}
+	if !isRemote(repoDir, remote) {
+		return append(args, "--remotes")
+	}
+	return append(args, "--remotes="+remote+"/*")
+}
+
+// isRemote reports whether name is a configured remote rather than a URL
+func isRemote(repoDir, name string) bool {
+	cmd := exec.Command("git", "remote")
+	cmd.Dir = repoDir
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	for _, remote := range strings.Split(string(output), "\n") {
+		if remote == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCommit(repoDir, sha string) bool {
+	cmd := exec.Command("git", "cat-file", "-e", sha+"^{commit}")
+	cmd.Dir = repoDir
+	return cmd.Run() == nil
+}
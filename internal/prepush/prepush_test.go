@@ -0,0 +1,114 @@
+package prepush_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/prepush"
+	"github.com/stretchr/testify/require"
+)
+
+const zero = "0000000000000000000000000000000000000000"
+
+func TestParse(t *testing.T) {
+	updates := prepush.Parse([]byte("refs/heads/main abc refs/heads/main def\n\nrefs/heads/gone " + zero + " refs/heads/gone abc\n"))
+	require.Equal(t, []prepush.Update{
+		{LocalRef: "refs/heads/main", LocalSHA: "abc", RemoteRef: "refs/heads/main", RemoteSHA: "def"},
+		{LocalRef: "refs/heads/gone", LocalSHA: zero, RemoteRef: "refs/heads/gone", RemoteSHA: "abc"},
+	}, updates)
+	require.False(t, updates[0].Deleted())
+	require.True(t, updates[1].Deleted())
+}
+
+func TestRevisions(t *testing.T) {
+	repo, origin := setupRepo(t)
+	pushed := commit(t, repo, "main.go", "feat: pushed")
+	git(t, repo, "push", "-q", "origin", "main")
+	local := commit(t, repo, "lib.go", "feat: local")
+
+	tests := []struct {
+		name      string
+		remote    string
+		remoteSHA string
+		want      []string
+	}{
+		{
+			name:      "existing ref",
+			remote:    "origin",
+			remoteSHA: pushed,
+			want:      []string{local, "--not", pushed},
+		},
+		{
+			name:      "new ref",
+			remote:    "origin",
+			remoteSHA: zero,
+			want:      []string{local, "--not", "--remotes=origin/*"},
+		},
+		{
+			name:      "remote commit missing locally",
+			remote:    "origin",
+			remoteSHA: strings.Repeat("1", 40),
+			want:      []string{local, "--not", "--remotes=origin/*"},
+		},
+		{
+			name:      "push to a URL",
+			remote:    origin,
+			remoteSHA: zero,
+			want:      []string{local, "--not", "--remotes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := prepush.Update{LocalRef: "refs/heads/main", LocalSHA: local, RemoteRef: "refs/heads/main", RemoteSHA: tt.remoteSHA}
+			revisions := u.Revisions(repo, tt.remote)
+			require.Equal(t, tt.want, revisions)
+
+			// Only the unpushed commit is selected
+			log := git(t, repo, append([]string{"log", "--format=%H"}, revisions...)...)
+			require.Equal(t, []string{local}, strings.Fields(log))
+		})
+	}
+}
+
+func setupRepo(t *testing.T) (string, string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	origin := filepath.Join(home, "origin.git")
+	git(t, home, "init", "-q", "--bare", origin)
+
+	repo := filepath.Join(home, "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	git(t, repo, "init", "-q", "-b", "main")
+	git(t, repo, "config", "user.name", "Test User")
+	git(t, repo, "config", "user.email", "test@example.com")
+	git(t, repo, "remote", "add", "origin", origin)
+	return repo, origin
+}
+
+// commit changes file and commits it with msg, returning the commit SHA
+func commit(t *testing.T, repo, file, msg string) string {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, file), []byte(msg+"\n"), 0o644))
+	git(t, repo, "add", file)
+	git(t, repo, "commit", "-q", "-m", msg)
+	return strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+	return string(output)
+}
@@ -0,0 +1,386 @@
+package provision
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+)
+
+const (
+	// DefaultVersion is the gitleaks release installed when none is pinned
+	DefaultVersion = "8.18.4"
+	// DefaultMirror serves the gitleaks releases as
+	// <mirror>/v<version>/<asset>
+	DefaultMirror = "https://github.com/gitleaks/gitleaks/releases/download"
+	// downloadTimeout bounds each request to the mirror, including the body
+	downloadTimeout = 5 * time.Minute
+)
+
+// ErrChecksum is returned when an archive doesn't match its checksum
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Options selects the gitleaks release to install and where from
+type Options struct {
+	// Version is the release without the v prefix
+	Version string
+	// Mirror is the base URL of the releases
+	Mirror string
+	// Checksums are the expected SHA-256 in hex by archive name. Without one
+	// for the platform's archive, the checksums file of the upstream release
+	// or the one next to Archive is used.
+	Checksums map[string]string
+	// Archive installs from a local release archive instead of downloading
+	Archive string
+	// CacheDir holds one directory per installed version
+	CacheDir string
+	// GOOS and GOARCH default to the running platform
+	GOOS, GOARCH string
+	Client       *http.Client
+	// Log receives progress messages
+	Log io.Writer
+}
+
+// FromConfig fills the options from git config `githooks.gitleaks.version`,
+// `githooks.gitleaks.mirror` and `githooks.gitleaks.checksum`, keeping the
+// fields already set. The checksum key can be repeated, one
+// `<sha256> <archive>` line of a release checksums file per platform.
+func FromConfig(dir string, opts Options) (Options, error) {
+	for key, field := range map[string]*string{
+		"githooks.gitleaks.version": &opts.Version,
+		"githooks.gitleaks.mirror":  &opts.Mirror,
+	} {
+		if *field != "" {
+			continue
+		}
+		value, _, err := gitconfig.Get(dir, gitconfig.ScopeDefault, key)
+		if err != nil {
+			return opts, err
+		}
+		*field = strings.TrimSpace(value)
+	}
+
+	if opts.Checksums != nil {
+		return opts, nil
+	}
+	entries, err := gitconfig.GetAll(dir, "githooks.gitleaks.checksum")
+	if err != nil {
+		return opts, err
+	}
+	for _, e := range entries {
+		sum, asset, ok := parseChecksum(e.Value)
+		if !ok {
+			return opts, fmt.Errorf("githooks.gitleaks.checksum: expected `<sha256> <archive>`, got %q", e.Value)
+		}
+		if opts.Checksums == nil {
+			opts.Checksums = map[string]string{}
+		}
+		opts.Checksums[asset] = sum
+	}
+	return opts, nil
+}
+
+// parseChecksum splits a line of a checksums file
+func parseChecksum(line string) (sum, asset string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+	return strings.ToLower(fields[0]), strings.TrimPrefix(fields[1], "*"), true
+}
+
+// CacheDir returns the gitleaks cache under the git-hooks home
+func CacheDir(hooksHome string) string {
+	return filepath.Join(hooksHome, "tools", "gitleaks")
+}
+
+// Gitleaks returns the path of the pinned gitleaks binary, installing it into
+// the cache first when it isn't there yet. Archives are verified before
+// anything is extracted from them.
+func Gitleaks(opts Options) (string, error) {
+	if opts.Archive != "" && opts.Version == "" {
+		opts.Version = archiveVersion(opts.Archive)
+	}
+	opts = opts.withDefaults()
+
+	binary := filepath.Join(opts.CacheDir, opts.Version, binaryName(opts.GOOS))
+	if _, err := os.Stat(binary); err == nil {
+		fmt.Fprintf(opts.Log, "Using cached gitleaks %s: %s\n", opts.Version, binary)
+		return binary, nil
+	}
+
+	asset, err := assetName(opts.Version, opts.GOOS, opts.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	if opts.Archive != "" && filepath.Base(opts.Archive) != asset {
+		// Checksums list the archive under its release name
+		return "", fmt.Errorf("%s is not the gitleaks %s release for %s/%s, expected %s",
+			opts.Archive, opts.Version, opts.GOOS, opts.GOARCH, asset)
+	}
+	want, err := opts.expectedChecksum(asset)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(binary), 0o755); err != nil {
+		return "", fmt.Errorf("creating gitleaks cache: %w", err)
+	}
+
+	archive := opts.Archive
+	if archive == "" {
+		tmp, err := os.CreateTemp(filepath.Dir(binary), asset+".*")
+		if err != nil {
+			return "", err
+		}
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		archive = tmp.Name()
+
+		url := opts.releaseURL(asset)
+		fmt.Fprintf(opts.Log, "Downloading gitleaks %s from %s\n", opts.Version, url)
+		if err := opts.download(url, archive); err != nil {
+			return "", err
+		}
+	}
+
+	if err := verify(archive, want); err != nil {
+		return "", fmt.Errorf("verifying %s: %w", asset, err)
+	}
+
+	if err := extract(archive, asset, binaryName(opts.GOOS), binary); err != nil {
+		return "", fmt.Errorf("extracting gitleaks from %s: %w", asset, err)
+	}
+	fmt.Fprintf(opts.Log, "Gitleaks %s installed at: %s\n", opts.Version, binary)
+	return binary, nil
+}
+
+func (o Options) withDefaults() Options {
+	o.Version = strings.TrimPrefix(o.Version, "v")
+	if o.Version == "" {
+		o.Version = DefaultVersion
+	}
+	if o.Mirror == "" {
+		o.Mirror = DefaultMirror
+	}
+	o.Mirror = strings.TrimSuffix(o.Mirror, "/")
+	if o.GOOS == "" {
+		o.GOOS = runtime.GOOS
+	}
+	if o.GOARCH == "" {
+		o.GOARCH = runtime.GOARCH
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: downloadTimeout}
+	}
+	if o.Log == nil {
+		o.Log = io.Discard
+	}
+	return o
+}
+
+func (o Options) releaseURL(asset string) string {
+	return fmt.Sprintf("%s/v%s/%s", o.Mirror, o.Version, asset)
+}
+
+// expectedChecksum returns the pinned checksum of asset, or the one the
+// release checksums file lists. Offline installs read the checksums file from
+// next to the archive. Downloads only use the upstream release's: a mirror's
+// checksums file catches corrupt downloads, not tampered archives.
+func (o Options) expectedChecksum(asset string) (string, error) {
+	if sum, ok := o.Checksums[asset]; ok {
+		return strings.ToLower(sum), nil
+	}
+
+	sums := fmt.Sprintf("gitleaks_%s_checksums.txt", o.Version)
+	var data []byte
+	switch {
+	case o.Archive != "":
+		var err error
+		data, err = os.ReadFile(filepath.Join(filepath.Dir(o.Archive), sums))
+		if err != nil {
+			return "", fmt.Errorf("no checksum to verify %s: set githooks.gitleaks.checksum or put %s next to it: %w", o.Archive, sums, err)
+		}
+	case o.Mirror != DefaultMirror:
+		return "", fmt.Errorf("no trusted checksum for %s from %s: set githooks.gitleaks.checksum to `<sha256> %s`", asset, o.Mirror, asset)
+	default:
+		var buf strings.Builder
+		if err := o.get(o.releaseURL(sums), &buf); err != nil {
+			return "", err
+		}
+		data = []byte(buf.String())
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		if sum, name, ok := parseChecksum(scanner.Text()); ok && name == asset {
+			return sum, nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", sums, asset)
+}
+
+func (o Options) download(url, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return o.get(url, file)
+}
+
+func (o Options) get(url string, w io.Writer) error {
+	resp, err := o.Client.Get(url)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("downloading %s: %w", url, err)
+	}
+	return nil
+}
+
+// assetName returns the release archive of gitleaks for the platform
+func assetName(version, goos, goarch string) (string, error) {
+	arch, ok := map[string]string{
+		"amd64": "x64",
+		"arm64": "arm64",
+		"386":   "x32",
+		"arm":   "armv7",
+	}[goarch]
+	if !ok || (goos != "linux" && goos != "darwin" && goos != "windows") {
+		return "", fmt.Errorf("no gitleaks release for %s/%s", goos, goarch)
+	}
+	ext := "tar.gz"
+	if goos == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("gitleaks_%s_%s_%s.%s", version, goos, arch, ext), nil
+}
+
+// archiveVersion returns the version in a release archive name like
+// gitleaks_8.18.4_linux_x64.tar.gz, or an empty string
+func archiveVersion(archive string) string {
+	parts := strings.Split(filepath.Base(archive), "_")
+	if len(parts) < 4 || parts[0] != "gitleaks" {
+		return ""
+	}
+	return parts[1]
+}
+
+func binaryName(goos string) string {
+	if goos == "windows" {
+		return "gitleaks.exe"
+	}
+	return "gitleaks"
+}
+
+func verify(path, want string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("%w: got sha256 %s, expected %s", ErrChecksum, got, want)
+	}
+	return nil
+}
+
+// extract writes the binary called name from the archive to dest. It is
+// written next to dest first so an interrupted install leaves no binary.
+func extract(archive, asset, name, dest string) error {
+	tmp := dest + ".tmp"
+	defer os.Remove(tmp)
+
+	var err error
+	if strings.HasSuffix(asset, ".zip") {
+		err = extractZip(archive, name, tmp)
+	} else {
+		err = extractTarGz(archive, name, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+func extractTarGz(archive, name, dest string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("no %s in archive", name)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == name {
+			return writeBinary(dest, tr)
+		}
+	}
+}
+
+func extractZip(archive, name, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != name || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return writeBinary(dest, rc)
+	}
+	return fmt.Errorf("no %s in archive", name)
+}
+
+func writeBinary(dest string, r io.Reader) error {
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,205 @@
+package provision_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/provision"
+	"github.com/stretchr/testify/require"
+)
+
+const asset = "gitleaks_8.18.4_linux_x64.tar.gz"
+
+func TestGitleaks_Download(t *testing.T) {
+	archive := releaseArchive(t, "8.18.4")
+	mirror, requests := releaseMirror(t, map[string][]byte{
+		"/v8.18.4/" + asset:                      archive,
+		"/v8.18.4/gitleaks_8.18.4_checksums.txt": checksums(asset, archive),
+	})
+	opts := provision.Options{Version: "v8.18.4", Mirror: mirror + "/", CacheDir: t.TempDir(), GOOS: "linux", GOARCH: "amd64"}
+
+	// The mirror's own checksums file can't tell a tampered archive
+	_, err := provision.Gitleaks(opts)
+	require.ErrorContains(t, err, "no trusted checksum for "+asset)
+	require.Zero(t, requests.Load())
+
+	opts.Checksums = map[string]string{asset: sha256Hex(archive)}
+	path, err := provision.Gitleaks(opts)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(opts.CacheDir, "8.18.4", "gitleaks"), path)
+	output, err := exec.Command(path, "version").Output()
+	require.NoError(t, err)
+	require.Equal(t, "8.18.4\n", string(output))
+	require.EqualValues(t, 1, requests.Load())
+
+	// The cached binary is used without network access
+	path, err = provision.Gitleaks(opts)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(opts.CacheDir, "8.18.4", "gitleaks"), path)
+	require.EqualValues(t, 1, requests.Load())
+
+	entries, err := os.ReadDir(filepath.Join(opts.CacheDir, "8.18.4"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "downloads are cleaned up")
+}
+
+func TestGitleaks_ChecksumMismatch(t *testing.T) {
+	archive := releaseArchive(t, "8.18.4")
+	tampered := releaseArchive(t, "evil")
+	mirror, _ := releaseMirror(t, map[string][]byte{
+		"/v8.18.4/" + asset:                      tampered,
+		"/v8.18.4/gitleaks_8.18.4_checksums.txt": checksums(asset, tampered),
+	})
+	opts := provision.Options{
+		Version:   "8.18.4",
+		Mirror:    mirror,
+		Checksums: map[string]string{asset: strings.ToUpper(sha256Hex(archive))},
+		CacheDir:  t.TempDir(),
+		GOOS:      "linux",
+		GOARCH:    "amd64",
+	}
+
+	// The pinned checksum wins over the mirror's checksums file
+	_, err := provision.Gitleaks(opts)
+	require.ErrorIs(t, err, provision.ErrChecksum)
+	require.NoFileExists(t, filepath.Join(opts.CacheDir, "8.18.4", "gitleaks"))
+
+	mirror, _ = releaseMirror(t, map[string][]byte{"/v8.18.4/" + asset: archive})
+	opts.Mirror = mirror
+	_, err = provision.Gitleaks(opts)
+	require.NoError(t, err)
+
+	// A checksum pinned for another platform doesn't verify this one
+	opts.CacheDir = t.TempDir()
+	opts.Checksums = map[string]string{"gitleaks_8.18.4_darwin_arm64.tar.gz": sha256Hex(archive)}
+	_, err = provision.Gitleaks(opts)
+	require.ErrorContains(t, err, "no trusted checksum for "+asset)
+}
+
+func TestGitleaks_MissingRelease(t *testing.T) {
+	mirror, _ := releaseMirror(t, nil)
+	opts := provision.Options{
+		Version:   "8.18.4",
+		Mirror:    mirror,
+		Checksums: map[string]string{asset: strings.Repeat("0", 64)},
+		CacheDir:  t.TempDir(),
+		GOOS:      "linux",
+		GOARCH:    "amd64",
+	}
+
+	_, err := provision.Gitleaks(opts)
+	require.ErrorContains(t, err, "404 Not Found")
+
+	opts.GOOS = "plan9"
+	_, err = provision.Gitleaks(opts)
+	require.ErrorContains(t, err, "no gitleaks release for plan9/amd64")
+}
+
+func TestGitleaks_FromArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := releaseArchive(t, "8.18.4")
+	archivePath := filepath.Join(dir, asset)
+	require.NoError(t, os.WriteFile(archivePath, archive, 0o644))
+	opts := provision.Options{Archive: archivePath, Mirror: "http://127.0.0.1:0", CacheDir: t.TempDir(), GOOS: "linux", GOARCH: "arm64"}
+
+	_, err := provision.Gitleaks(opts)
+	require.ErrorContains(t, err, "is not the gitleaks 8.18.4 release for linux/arm64, expected gitleaks_8.18.4_linux_arm64.tar.gz")
+
+	opts.GOARCH = "amd64"
+	_, err = provision.Gitleaks(opts)
+	require.ErrorContains(t, err, "no checksum to verify")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gitleaks_8.18.4_checksums.txt"), checksums(asset, archive), 0o644))
+	path, err := provision.Gitleaks(opts)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(opts.CacheDir, "8.18.4", "gitleaks"), path, "version comes from the archive name")
+}
+
+func TestFromConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, kv := range [][2]string{
+		{"githooks.gitleaks.version", "8.19.0"},
+		{"githooks.gitleaks.mirror", "https://mirror.example.com/gitleaks"},
+		{"githooks.gitleaks.checksum", "ABC  gitleaks_8.18.4_linux_x64.tar.gz"},
+		{"githooks.gitleaks.checksum", "def *gitleaks_8.18.4_darwin_arm64.tar.gz"},
+	} {
+		out, err := exec.Command("git", "config", "--global", "--add", kv[0], kv[1]).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	opts, err := provision.FromConfig(home, provision.Options{Version: "8.18.4"})
+	require.NoError(t, err)
+	require.Equal(t, provision.Options{
+		Version: "8.18.4",
+		Mirror:  "https://mirror.example.com/gitleaks",
+		Checksums: map[string]string{
+			"gitleaks_8.18.4_linux_x64.tar.gz":    "abc",
+			"gitleaks_8.18.4_darwin_arm64.tar.gz": "def",
+		},
+	}, opts)
+
+	out, err := exec.Command("git", "config", "--global", "--add", "githooks.gitleaks.checksum", "abc").CombinedOutput()
+	require.NoError(t, err, string(out))
+	_, err = provision.FromConfig(home, provision.Options{})
+	require.ErrorContains(t, err, "expected `<sha256> <archive>`")
+}
+
+// releaseArchive returns a release tar.gz whose gitleaks prints version
+func releaseArchive(t *testing.T, version string) []byte {
+	t.Helper()
+
+	script := []byte("#!/bin/sh\necho " + version + "\n")
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string][]byte{"README.md": []byte("gitleaks\n"), "gitleaks": script} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func checksums(name string, data []byte) []byte {
+	return []byte(sha256Hex(data) + "  " + name + "\n" +
+		strings.Repeat("0", 64) + "  gitleaks_8.18.4_darwin_arm64.tar.gz\n")
+}
+
+// releaseMirror serves files by path, counting the requests
+func releaseMirror(t *testing.T, files map[string][]byte) (string, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
@@ -0,0 +1,88 @@
+package repoconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the per-repository configuration file, committed at the
+// repository root
+const FileName = ".git-hooks.yaml"
+
+// Config is the content of .git-hooks.yaml
+type Config struct {
+	Levels   Levels             `yaml:"levels"`
+	Hooks    map[string]Hook    `yaml:"hooks"`
+	Trailers map[string]Trailer `yaml:"trailers"`
+	// VerifyScanned configures `git-hooks verify-scanned`
+	VerifyScanned VerifyScanned `yaml:"verify-scanned"`
+}
+
+// Levels configures which hook levels run and in which order. A nil list
+// leaves the git config setting in effect.
+type Levels struct {
+	// Order lists levels in the order they run
+	Order []string `yaml:"order"`
+	// Disable lists levels that never run
+	Disable []string `yaml:"disable"`
+}
+
+// Hook adjusts the scripts run for one hook
+type Hook struct {
+	// Disable lists scripts that must not run in this repository
+	Disable []string `yaml:"disable"`
+	// Override replaces scripts by name with a command run from the
+	// repository root
+	Override map[string]string `yaml:"override"`
+	// Severity sets error, warn or off for scripts by name
+	Severity map[string]string `yaml:"severity"`
+}
+
+// Trailer declares a trailer added to commit messages. Fields left empty
+// keep the git config value of the trailer with the same name.
+type Trailer struct {
+	Key string `yaml:"key"`
+	// Value is a text/template
+	Value string `yaml:"value"`
+	// Hook is commit-msg or prepare-commit-msg, separated by spaces for both
+	Hook string `yaml:"hook"`
+	// Policy is if-missing, replace or append
+	Policy string `yaml:"policy"`
+	// Disable drops a trailer declared in git config for this repository
+	Disable bool `yaml:"disable"`
+}
+
+// VerifyScanned lists the commits that don't need a Scanned-by trailer
+type VerifyScanned struct {
+	Allow Allow `yaml:"allow"`
+}
+
+// Allow exempts commits by author, changed paths or being a merge
+type Allow struct {
+	Authors []string `yaml:"authors"`
+	Paths   []string `yaml:"paths"`
+	Merges  bool     `yaml:"merges"`
+}
+
+// Load reads .git-hooks.yaml from repoDir. A missing file is an empty config.
+func Load(repoDir string) (Config, error) {
+	var cfg Config
+
+	path := filepath.Join(repoDir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,251 @@
+package scanned
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/glob"
+	"github.com/rudderlabs/git-hooks/internal/plural"
+	"github.com/rudderlabs/git-hooks/internal/repoconfig"
+	"github.com/rudderlabs/git-hooks/internal/trailers"
+)
+
+// Allowlist exempts commits that never go through the local scan, such as
+// bot commits and merges made on the server
+type Allowlist struct {
+	// Authors are globs matched against the author name, email and
+	// `Name <email>`
+	Authors []string `json:"authors,omitempty"`
+	// Paths are globs; commits only touching matching files are allowed
+	Paths []string `json:"paths,omitempty"`
+	// Merges allows all merge commits
+	Merges bool `json:"merges,omitempty"`
+}
+
+// Options configures which commits are verified
+type Options struct {
+	RepoDir string
+	// Revisions are git log arguments, e.g. `origin/main..HEAD`
+	Revisions []string
+	// Key is the trailer every commit must have
+	Key   string
+	Allow Allowlist
+}
+
+// Commit is a verified commit that has no trailer
+type Commit struct {
+	SHA     string `json:"sha"`
+	Author  string `json:"author"`
+	Subject string `json:"subject"`
+	// Reason is why a commit without trailer is allowed
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report is the outcome of verifying a range of commits
+type Report struct {
+	Key     string   `json:"key"`
+	Ranges  []string `json:"ranges"`
+	Checked int      `json:"checked"`
+	Scanned int      `json:"scanned"`
+	Allowed []Commit `json:"allowed"`
+	Missing []Commit `json:"missing"`
+}
+
+// Failed reports whether any commit is missing the trailer
+func (r Report) Failed() bool {
+	return len(r.Missing) > 0
+}
+
+// Merge adds the commits of other, e.g. for another ref of the same push
+func (r *Report) Merge(other Report) {
+	r.Ranges = append(r.Ranges, other.Ranges...)
+	r.Checked += other.Checked
+	r.Scanned += other.Scanned
+	r.Allowed = append(r.Allowed, other.Allowed...)
+	r.Missing = append(r.Missing, other.Missing...)
+}
+
+// LoadAllowlist returns the allowlist from `githooks.verifyScanned.*` in git
+// config, combined with the verify-scanned section of .git-hooks.yaml when
+// repoConfig is set. The checked-out .git-hooks.yaml is part of the commits
+// being verified, so CI should leave it out.
+func LoadAllowlist(repoDir string, repoConfig bool) (Allowlist, error) {
+	var allow Allowlist
+	for key, dst := range map[string]*[]string{
+		"githooks.verifyScanned.allowAuthor": &allow.Authors,
+		"githooks.verifyScanned.allowPath":   &allow.Paths,
+	} {
+		entries, err := gitconfig.GetAll(repoDir, key)
+		if err != nil {
+			return allow, err
+		}
+		for _, e := range entries {
+			*dst = append(*dst, e.Value)
+		}
+	}
+
+	value, ok, err := gitconfig.Get(repoDir, gitconfig.ScopeDefault, "githooks.verifyScanned.allowMerges")
+	if err != nil {
+		return allow, err
+	}
+	if ok {
+		if allow.Merges, err = strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+			return allow, fmt.Errorf("githooks.verifyScanned.allowMerges: %w", err)
+		}
+	}
+	if !repoConfig {
+		return allow, nil
+	}
+
+	cfg, err := repoconfig.Load(repoDir)
+	if err != nil {
+		return allow, err
+	}
+	allow.Authors = append(allow.Authors, cfg.VerifyScanned.Allow.Authors...)
+	allow.Paths = append(allow.Paths, cfg.VerifyScanned.Allow.Paths...)
+	allow.Merges = allow.Merges || cfg.VerifyScanned.Allow.Merges
+	return allow, nil
+}
+
+// Verify checks that every commit selected by opts.Revisions has the
+// opts.Key trailer, or is allowlisted
+func Verify(opts Options) (Report, error) {
+	report := Report{
+		Key:     opts.Key,
+		Ranges:  []string{strings.Join(opts.Revisions, " ")},
+		Allowed: []Commit{},
+		Missing: []Commit{},
+	}
+
+	args := append([]string{"log", "--format=%H%x00%P%x00%an%x00%ae%x00%B%x1e"}, opts.Revisions...)
+	output, err := git(opts.RepoDir, append(args, "--")...)
+	if err != nil {
+		return report, fmt.Errorf("listing commits of %s: %w", strings.Join(opts.Revisions, " "), err)
+	}
+
+	commentChar := trailers.CommentChar(opts.RepoDir, "")
+	for _, record := range strings.Split(string(output), "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x00", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		sha, parents, name, email, body := fields[0], strings.Fields(fields[1]), fields[2], fields[3], fields[4]
+		report.Checked++
+
+		msg := trailers.Parse(body, trailers.Options{
+			CommentChar:  commentChar,
+			Conventional: true,
+		})
+		if msg.Has(opts.Key) {
+			report.Scanned++
+			continue
+		}
+
+		subject, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+		commit := Commit{SHA: sha, Author: fmt.Sprintf("%s <%s>", name, email), Subject: subject}
+		if commit.Reason, err = allowed(opts, sha, parents, name, email); err != nil {
+			return report, err
+		}
+		if commit.Reason != "" {
+			report.Allowed = append(report.Allowed, commit)
+		} else {
+			report.Missing = append(report.Missing, commit)
+		}
+	}
+	return report, nil
+}
+
+// allowed returns why the allowlist exempts a commit, or ""
+func allowed(opts Options, sha string, parents []string, name, email string) (string, error) {
+	allow := opts.Allow
+	if allow.Merges && len(parents) > 1 {
+		return "merge commit", nil
+	}
+	for _, author := range []string{email, name, fmt.Sprintf("%s <%s>", name, email)} {
+		if glob.MatchAny(allow.Authors, author) {
+			return "allowed author", nil
+		}
+	}
+
+	if len(allow.Paths) == 0 {
+		return "", nil
+	}
+	args := []string{"diff-tree", "--no-commit-id", "--name-only", "-r", "--root", sha}
+	if len(parents) > 1 {
+		// Files the merge brought into the first parent
+		args = []string{"diff", "--name-only", parents[0], sha}
+	}
+	output, err := git(opts.RepoDir, args...)
+	if err != nil {
+		return "", fmt.Errorf("listing files of %s: %w", sha, err)
+	}
+	paths := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if paths[0] == "" {
+		return "", nil
+	}
+	for _, path := range paths {
+		if !glob.MatchAny(allow.Paths, path) {
+			return "", nil
+		}
+	}
+	return "allowed paths", nil
+}
+
+// WriteText renders the report for humans
+func (r Report) WriteText(w io.Writer) {
+	for _, c := range r.Missing {
+		fmt.Fprintf(w, "❌ %s %s (%s): no %s trailer\n", short(c.SHA), c.Subject, c.Author, r.Key)
+	}
+	for _, c := range r.Allowed {
+		fmt.Fprintf(w, "➖ %s %s (%s): %s\n", short(c.SHA), c.Subject, c.Author, c.Reason)
+	}
+	fmt.Fprintf(w, "Checked %d %s: %d scanned, %d allowed, %d missing %s\n",
+		r.Checked, plural.Of(r.Checked, "commit", "commits"), r.Scanned, len(r.Allowed), len(r.Missing), r.Key)
+}
+
+// WriteGitHub renders the report as GitHub Actions workflow commands, which
+// show up as annotations on the run
+func (r Report) WriteGitHub(w io.Writer) {
+	title := "Missing " + r.Key + " trailer"
+	for _, c := range r.Missing {
+		message := fmt.Sprintf("Commit %s (%s) by %s has no %s trailer, it did not go through the local scan",
+			short(c.SHA), c.Subject, c.Author, r.Key)
+		fmt.Fprintf(w, "::error title=%s::%s\n", escapeProperty(title), escapeData(message))
+	}
+	summary := fmt.Sprintf("Checked %d %s: %d scanned, %d allowed, %d missing %s",
+		r.Checked, plural.Of(r.Checked, "commit", "commits"), r.Scanned, len(r.Allowed), len(r.Missing), r.Key)
+	fmt.Fprintf(w, "::notice title=verify-scanned::%s\n", escapeData(summary))
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func short(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return output, err
+}
@@ -0,0 +1,154 @@
+package scanned_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/scanned"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	repo := setupRepo(t)
+	base := commit(t, repo, "README.md", "chore: init\n\nScanned-by: gitleaks v8.18.0")
+	commit(t, repo, "main.go", "feat: scanned\n\nScanned-by: gitleaks v8.18.0")
+	missing := commit(t, repo, "main.go", "feat: not scanned")
+	commit(t, repo, "docs/guide.md", "docs: guide")
+	git(t, repo, "-c", "user.name=renovate[bot]", "-c", "user.email=bot@renovateapp.com", "commit", "--allow-empty", "-m", "chore(deps): bump")
+	git(t, repo, "checkout", "-q", "-b", "topic", base)
+	commit(t, repo, "lib.go", "feat: topic\n\nScanned-by: gitleaks v8.18.0")
+	git(t, repo, "checkout", "-q", "main")
+	git(t, repo, "merge", "--no-ff", "-m", "Merge branch 'topic'", "topic")
+
+	t.Log("Without allowlist every commit without trailer is missing")
+	report, err := scanned.Verify(scanned.Options{RepoDir: repo, Revisions: []string{base + "..HEAD"}, Key: "Scanned-by"})
+	require.NoError(t, err)
+	require.Equal(t, 6, report.Checked)
+	require.Equal(t, 2, report.Scanned)
+	require.Len(t, report.Missing, 4)
+	require.True(t, report.Failed())
+
+	t.Log("The allowlist exempts bots, docs-only commits and merges")
+	report, err = scanned.Verify(scanned.Options{
+		RepoDir:   repo,
+		Revisions: []string{base + "..HEAD"},
+		Key:       "Scanned-by",
+		Allow: scanned.Allowlist{
+			Authors: []string{"*[bot]"},
+			Paths:   []string{"docs/**", "**/*.md"},
+			Merges:  true,
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Missing, 1)
+	require.Equal(t, missing, report.Missing[0].SHA)
+	require.Equal(t, "feat: not scanned", report.Missing[0].Subject)
+	require.Equal(t, "Test User <test@example.com>", report.Missing[0].Author)
+
+	reasons := map[string]string{}
+	for _, c := range report.Allowed {
+		reasons[c.Subject] = c.Reason
+	}
+	require.Equal(t, map[string]string{
+		"docs: guide":          "allowed paths",
+		"chore(deps): bump":    "allowed author",
+		"Merge branch 'topic'": "merge commit",
+	}, reasons)
+
+	var text bytes.Buffer
+	report.WriteText(&text)
+	require.Contains(t, text.String(), "❌ "+missing[:12]+" feat: not scanned (Test User <test@example.com>): no Scanned-by trailer")
+	require.Contains(t, text.String(), "Checked 6 commits: 2 scanned, 3 allowed, 1 missing Scanned-by")
+
+	t.Log("Invalid ranges are reported")
+	_, err = scanned.Verify(scanned.Options{RepoDir: repo, Revisions: []string{"nope..HEAD"}, Key: "Scanned-by"})
+	require.ErrorContains(t, err, "listing commits of nope..HEAD")
+}
+
+func TestWriteGitHub(t *testing.T) {
+	report := scanned.Report{
+		Key:     "Scanned-by",
+		Checked: 1,
+		Missing: []scanned.Commit{{SHA: "0123456789abcdef", Author: "A <a@example.com>", Subject: "fix: 100% done"}},
+	}
+
+	var out bytes.Buffer
+	report.WriteGitHub(&out)
+	require.Equal(t, "::error title=Missing Scanned-by trailer::Commit 0123456789ab (fix: 100%25 done) by A <a@example.com> has no Scanned-by trailer, it did not go through the local scan\n"+
+		"::notice title=verify-scanned::Checked 1 commit: 0 scanned, 0 allowed, 1 missing Scanned-by\n", out.String())
+}
+
+func TestLoadAllowlist(t *testing.T) {
+	repo := setupRepo(t)
+	git(t, repo, "config", "--add", "githooks.verifyScanned.allowAuthor", "*[bot]")
+	git(t, repo, "config", "--add", "githooks.verifyScanned.allowAuthor", "ci@example.com")
+	git(t, repo, "config", "githooks.verifyScanned.allowMerges", "true")
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte(`
+verify-scanned:
+  allow:
+    paths: ["docs/**"]
+`), 0o644))
+
+	allow, err := scanned.LoadAllowlist(repo, true)
+	require.NoError(t, err)
+	require.Equal(t, scanned.Allowlist{
+		Authors: []string{"*[bot]", "ci@example.com"},
+		Paths:   []string{"docs/**"},
+		Merges:  true,
+	}, allow)
+
+	t.Log("The repository file can be left out, e.g. in CI")
+	allow, err = scanned.LoadAllowlist(repo, false)
+	require.NoError(t, err)
+	require.Empty(t, allow.Paths)
+	require.Len(t, allow.Authors, 2)
+}
+
+// Helper functions
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := filepath.Join(home, "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	git(t, repo, "init", "-q", "-b", "main")
+	git(t, repo, "config", "user.name", "Test User")
+	git(t, repo, "config", "user.email", "test@example.com")
+	return repo
+}
+
+// commit changes file and commits it with msg, returning the commit SHA
+func commit(t *testing.T, repo, file, msg string) string {
+	t.Helper()
+
+	path := filepath.Join(repo, file)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(msg + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	git(t, repo, "add", file)
+	git(t, repo, "commit", "-q", "-m", msg)
+	return strings.TrimSpace(git(t, repo, "rev-parse", "HEAD"))
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+	return string(output)
+}
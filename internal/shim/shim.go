@@ -0,0 +1,411 @@
+package shim
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	_ "embed"
+)
+
+//go:embed hook.sh
+var hookScript string
+
+// hookScriptV1 is the shim before values were single-quoted. Shims rendered
+// from it are still pristine and updated in place.
+//
+//go:embed hook-v1.sh
+var hookScriptV1 string
+
+var (
+	hookTemplate   = template.Must(template.New("hook.sh").Funcs(template.FuncMap{"quote": shellQuote}).Parse(hookScript))
+	hookTemplateV1 = template.Must(template.New("hook-v1.sh").Parse(hookScriptV1))
+)
+
+const (
+	versionPrefix = "# git-hooks-version: "
+	binaryPrefix  = "# git-hooks-binary: "
+)
+
+// Hooks are the hook names git runs, see githooks(5)
+var Hooks = []string{
+	"applypatch-msg", "pre-applypatch", "post-applypatch",
+	"pre-commit", "pre-merge-commit", "prepare-commit-msg", "commit-msg", "post-commit",
+	"pre-rebase", "post-checkout", "post-merge", "pre-push", "pre-receive",
+	"update", "proc-receive", "post-receive", "post-update", "reference-transaction",
+	"push-to-checkout", "pre-auto-gc", "post-rewrite", "sendemail-validate",
+	"fsmonitor-watchman", "p4-changelist", "p4-prepare-changelist", "p4-post-changelist", "p4-pre-submit",
+	"post-index-change",
+}
+
+// legacyShim matches the single exec line written by git-hooks before shims
+// recorded their version
+var legacyShim = regexp.MustCompile(`^"(.+)" hook (\S+) "\$@"$`)
+
+// homeLine and systemDirLine recover the optional params of a rendered shim,
+// the V1 ones those of a shim rendered from hook-v1.sh
+var (
+	homeLine        = regexp.MustCompile(`(?m)^    GIT_HOOKS_HOME=('.*')$`)
+	systemDirLine   = regexp.MustCompile(`(?m)^GIT_HOOKS_SYSTEM_DIR=('.*')$`)
+	homeLineV1      = regexp.MustCompile(`(?m)^GIT_HOOKS_HOME="\$\{GIT_HOOKS_HOME:-(.*)\}"$`)
+	systemDirLineV1 = regexp.MustCompile(`(?m)^GIT_HOOKS_SYSTEM_DIR="(.*)"$`)
+)
+
+var ErrNotShim = errors.New("not a git-hooks shim")
+
+// Params describes the binary a shim dispatches to
+type Params struct {
+	BinaryPath string
+	Version    string
+	// Home is exported as GIT_HOOKS_HOME unless the caller already set it
+	Home string
+	// SystemDir is exported as GIT_HOOKS_SYSTEM_DIR for system-wide shims
+	SystemDir string
+}
+
+// Info is the metadata recorded in an installed shim
+type Info struct {
+	HookName   string
+	BinaryPath string
+	Version    string
+}
+
+// Stale reports whether the shim no longer points at the binary described by p
+func (i Info) Stale(p Params) bool {
+	if i.Version != p.Version {
+		return true
+	}
+	return !SameBinary(i.BinaryPath, p.BinaryPath)
+}
+
+// SameBinary reports whether both paths resolve to the same file
+func SameBinary(a, b string) bool {
+	if a == b {
+		return true
+	}
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// Current returns the params for the running git-hooks binary. A git-hooks
+// found in PATH is preferred over the resolved executable when both are the
+// same file, so that package manager symlinks survive upgrades.
+func Current(version string) (Params, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return Params{}, fmt.Errorf("finding executable path: %w", err)
+	}
+
+	// Resolve symlinks to get the actual binary path
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return Params{}, fmt.Errorf("resolving executable symlinks: %w", err)
+	}
+
+	if inPath, err := exec.LookPath("git-hooks"); err == nil {
+		if inPath, err = filepath.Abs(inPath); err == nil && SameBinary(inPath, exe) {
+			exe = inPath
+		}
+	}
+
+	return Params{BinaryPath: exe, Version: version}, nil
+}
+
+// Render writes the shim for hook to w. The paths are single-quoted for the
+// shell, only a line break could still escape its comment lines.
+func Render(w io.Writer, hook string, p Params) error {
+	return render(hookTemplate, w, hook, p)
+}
+
+func render(tmpl *template.Template, w io.Writer, hook string, p Params) error {
+	for _, path := range []string{p.BinaryPath, p.Home, p.SystemDir} {
+		if strings.ContainsAny(path, "\r\n") {
+			return fmt.Errorf("path %q contains a line break", path)
+		}
+	}
+	return tmpl.Execute(w, struct {
+		HookName   string
+		BinaryPath string
+		Version    string
+		Home       string
+		SystemDir  string
+	}{
+		HookName:   hook,
+		BinaryPath: p.BinaryPath,
+		Version:    p.Version,
+		Home:       p.Home,
+		SystemDir:  p.SystemDir,
+	})
+}
+
+// Action describes what Install did to a shim
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionUnchanged Action = "unchanged"
+)
+
+// Change is the outcome of installing a single shim
+type Change struct {
+	Hook   string
+	Action Action
+	// MigratedTo is the new location of a user script that was in the way
+	MigratedTo string
+}
+
+// Write atomically creates or replaces the shim for hook in dir
+func Write(dir, hook string, p Params) error {
+	var buf bytes.Buffer
+	if err := Render(&buf, hook, p); err != nil {
+		return fmt.Errorf("executing template for %s: %w", hook, err)
+	}
+	if err := writeAtomic(filepath.Join(dir, hook), buf.Bytes()); err != nil {
+		return fmt.Errorf("writing script file for %s: %w", hook, err)
+	}
+	return nil
+}
+
+// Install writes the shim for hook in dir. Unmodified shims are left alone and
+// files that are not git-hooks shims, or shims edited by hand, are moved into <hook>.d/00-migrated, or
+// into a backup folder if that is taken, instead of being overwritten.
+func Install(dir, hook string, p Params) (Change, error) {
+	change := Change{Hook: hook}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, hook, p); err != nil {
+		return change, fmt.Errorf("executing template for %s: %w", hook, err)
+	}
+
+	scriptPath := filepath.Join(dir, hook)
+	existing, err := os.ReadFile(scriptPath)
+	switch {
+	case os.IsNotExist(err):
+		change.Action = ActionCreated
+	case err != nil:
+		return change, fmt.Errorf("reading existing script for %s: %w", hook, err)
+	case bytes.Equal(existing, buf.Bytes()) && isExecutable(scriptPath):
+		change.Action = ActionUnchanged
+		return change, nil
+	default:
+		change.Action = ActionUpdated
+		if !pristine(existing, hook) {
+			// An edited shim would dispatch the hook again from <hook>.d
+			_, isShim := Parse(existing)
+			if change.MigratedTo, err = migrate(dir, hook, isShim); err != nil {
+				return change, err
+			}
+			change.Action = ActionCreated
+		}
+	}
+
+	if err := writeAtomic(scriptPath, buf.Bytes()); err != nil {
+		return change, fmt.Errorf("writing script file for %s: %w", hook, err)
+	}
+	return change, nil
+}
+
+// migrate moves a user script out of the way of the shim for hook. Executable
+// scripts keep running from <hook>.d/00-migrated when that slot is free,
+// unless backup is set.
+func migrate(dir, hook string, backup bool) (string, error) {
+	scriptPath := filepath.Join(dir, hook)
+
+	target := filepath.Join(dir, hook+".d", "00-migrated")
+	if _, err := os.Lstat(target); backup || !isExecutable(scriptPath) || err == nil {
+		backup, err := backupPath(dir, hook)
+		if err != nil {
+			return "", fmt.Errorf("creating backup for %s: %w", hook, err)
+		}
+		target = backup
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("creating directory for migrated %s: %w", hook, err)
+	}
+	if err := os.Rename(scriptPath, target); err != nil {
+		return "", fmt.Errorf("migrating existing %s: %w", hook, err)
+	}
+	return target, nil
+}
+
+// backupPath reserves a new file in the backup folder of dir for hook. The
+// random suffix keeps backups taken within the same second apart.
+func backupPath(dir, hook string) (string, error) {
+	backupDir := filepath.Join(dir, ".backup")
+	if err := os.MkdirAll(backupDir, 0o755); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(backupDir, fmt.Sprintf("%s.%s-*", hook, time.Now().Format("20060102-150405")))
+	if err != nil {
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+// pristine reports whether content is a shim for hook exactly as git-hooks
+// wrote it, by rendering the template again from the values it records.
+// Legacy single-line shims are pristine when they hold nothing else.
+func pristine(content []byte, hook string) bool {
+	info, ok := Parse(content)
+	if !ok || info.HookName != hook {
+		return false
+	}
+
+	if info.Version == "" && !bytes.Contains(content, []byte(binaryPrefix)) {
+		for _, line := range strings.Split(string(content), "\n") {
+			if line != "" && !strings.HasPrefix(line, "#") && !legacyShim.MatchString(line) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, form := range []struct {
+		tmpl            *template.Template
+		home, systemDir *regexp.Regexp
+		unquote         func(string) string
+	}{
+		{hookTemplate, homeLine, systemDirLine, shellUnquote},
+		{hookTemplateV1, homeLineV1, systemDirLineV1, func(s string) string { return s }},
+	} {
+		p := Params{BinaryPath: info.BinaryPath, Version: info.Version}
+		if m := form.home.FindSubmatch(content); m != nil {
+			p.Home = form.unquote(string(m[1]))
+		}
+		if m := form.systemDir.FindSubmatch(content); m != nil {
+			p.SystemDir = form.unquote(string(m[1]))
+		}
+		var buf bytes.Buffer
+		if err := render(form.tmpl, &buf, hook, p); err == nil && bytes.Equal(content, buf.Bytes()) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes s as a single shell word that expands to nothing else
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellUnquote reverses shellQuote
+func shellUnquote(s string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "'"), "'")
+	return strings.ReplaceAll(s, `'\''`, "'")
+}
+
+// writeAtomic replaces path with an executable file holding content, so a
+// concurrently running hook never sees a partially written script
+func writeAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o755); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0
+}
+
+// Read parses the shim at path. It returns ErrNotShim if the file was not
+// written by git-hooks.
+func Read(path string) (Info, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	info, ok := Parse(content)
+	if !ok {
+		return Info{}, ErrNotShim
+	}
+	return info, nil
+}
+
+// Parse extracts the shim metadata from content. Shims written by older
+// versions are recognised but carry no version.
+func Parse(content []byte) (Info, bool) {
+	var info Info
+	var isShim bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, versionPrefix):
+			info.Version = strings.TrimPrefix(line, versionPrefix)
+		case strings.HasPrefix(line, binaryPrefix):
+			info.BinaryPath = strings.TrimPrefix(line, binaryPrefix)
+		case strings.HasPrefix(line, `exec "$GIT_HOOKS_BIN" hook `):
+			fields := strings.Fields(strings.TrimPrefix(line, `exec "$GIT_HOOKS_BIN" hook `))
+			if len(fields) > 0 {
+				info.HookName = fields[0]
+				isShim = true
+			}
+		default:
+			if m := legacyShim.FindStringSubmatch(line); m != nil {
+				info.BinaryPath = m[1]
+				info.HookName = m[2]
+				isShim = true
+			}
+		}
+	}
+
+	return info, isShim && info.BinaryPath != ""
+}
+
+// Repair rewrites every shim in dir that is missing or stale with respect to
+// p. Files that are not git-hooks shims are left alone. It returns the names
+// of the hooks that were rewritten.
+func Repair(dir string, hooks []string, p Params) ([]string, error) {
+	var repaired []string
+	for _, hook := range hooks {
+		info, err := Read(filepath.Join(dir, hook))
+		switch {
+		case errors.Is(err, ErrNotShim):
+			continue
+		case err != nil && !os.IsNotExist(err):
+			return repaired, fmt.Errorf("reading shim for %s: %w", hook, err)
+		case err == nil && !info.Stale(p):
+			continue
+		}
+
+		if err := Write(dir, hook, p); err != nil {
+			return repaired, err
+		}
+		repaired = append(repaired, hook)
+	}
+	return repaired, nil
+}
@@ -0,0 +1,282 @@
+package shim_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/shim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_RoundTrip(t *testing.T) {
+	params := shim.Params{BinaryPath: "/usr/local/bin/git-hooks", Version: "v1.2.0"}
+
+	var buf bytes.Buffer
+	require.NoError(t, shim.Render(&buf, "pre-commit", params))
+
+	info, ok := shim.Parse(buf.Bytes())
+	require.True(t, ok)
+	require.Equal(t, "pre-commit", info.HookName)
+	require.Equal(t, params.BinaryPath, info.BinaryPath)
+	require.Equal(t, params.Version, info.Version)
+	require.False(t, info.Stale(params))
+}
+
+func TestParse_LegacyShim(t *testing.T) {
+	legacy := "#!/bin/sh\n# Run git-hooks with the full path\n\"/opt/homebrew/Cellar/git-hooks/1.1.4/bin/git-hooks\" hook commit-msg \"$@\""
+
+	info, ok := shim.Parse([]byte(legacy))
+	require.True(t, ok)
+	require.Equal(t, "commit-msg", info.HookName)
+	require.Equal(t, "/opt/homebrew/Cellar/git-hooks/1.1.4/bin/git-hooks", info.BinaryPath)
+	require.Empty(t, info.Version)
+	require.True(t, info.Stale(shim.Params{BinaryPath: info.BinaryPath, Version: "v1.2.0"}))
+}
+
+func TestParse_UserScript(t *testing.T) {
+	_, ok := shim.Parse([]byte("#!/bin/sh\nnpm test\n"))
+	require.False(t, ok)
+}
+
+func TestStale(t *testing.T) {
+	tempDir := t.TempDir()
+	binary := filepath.Join(tempDir, "git-hooks")
+	link := filepath.Join(tempDir, "link")
+	require.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.Symlink(binary, link))
+
+	info := shim.Info{BinaryPath: link, Version: "v1.0.0"}
+
+	t.Log("Symlinks to the same binary are not stale")
+	require.False(t, info.Stale(shim.Params{BinaryPath: binary, Version: "v1.0.0"}))
+
+	t.Log("A version change is stale")
+	require.True(t, info.Stale(shim.Params{BinaryPath: binary, Version: "v1.1.0"}))
+
+	t.Log("A missing binary is stale")
+	require.True(t, info.Stale(shim.Params{BinaryPath: filepath.Join(tempDir, "missing"), Version: "v1.0.0"}))
+}
+
+func TestRepair(t *testing.T) {
+	tempDir := t.TempDir()
+	old := shim.Params{BinaryPath: filepath.Join(tempDir, "old", "git-hooks"), Version: "v1.0.0"}
+	current := shim.Params{BinaryPath: filepath.Join(tempDir, "new", "git-hooks"), Version: "v1.1.0"}
+
+	require.NoError(t, shim.Write(tempDir, "pre-commit", old))
+	require.NoError(t, shim.Write(tempDir, "pre-push", current))
+	userScript := filepath.Join(tempDir, "commit-msg")
+	require.NoError(t, os.WriteFile(userScript, []byte("#!/bin/sh\necho custom\n"), 0o755))
+
+	repaired, err := shim.Repair(tempDir, []string{"pre-commit", "pre-push", "commit-msg", "post-merge"}, current)
+	require.NoError(t, err)
+	require.Equal(t, []string{"pre-commit", "post-merge"}, repaired)
+
+	t.Log("Stale shim now points at the current binary")
+	info, err := shim.Read(filepath.Join(tempDir, "pre-commit"))
+	require.NoError(t, err)
+	require.False(t, info.Stale(current))
+
+	t.Log("User scripts are left untouched")
+	content, err := os.ReadFile(userScript)
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh\necho custom\n", string(content))
+}
+
+func TestShim_FallsBackToPath(t *testing.T) {
+	tempDir := t.TempDir()
+	binDir := filepath.Join(tempDir, "bin")
+	require.NoError(t, os.MkdirAll(binDir, 0o755))
+
+	// Fake git-hooks in PATH that echoes its arguments and the shim path
+	fake := filepath.Join(binDir, "git-hooks")
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\necho \"$@|$GIT_HOOKS_SHIM\"\n"), 0o755))
+
+	params := shim.Params{BinaryPath: filepath.Join(tempDir, "moved", "git-hooks"), Version: "v1.0.0"}
+	require.NoError(t, shim.Write(tempDir, "commit-msg", params))
+
+	shimPath := filepath.Join(tempDir, "commit-msg")
+	cmd := exec.Command(shimPath, ".git/COMMIT_EDITMSG")
+	cmd.Env = append(os.Environ(), "PATH="+binDir+":/usr/bin:/bin")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	require.Equal(t, "hook commit-msg .git/COMMIT_EDITMSG|"+shimPath, strings.TrimSpace(string(output)))
+}
+
+func TestShim_BinaryNotFound(t *testing.T) {
+	tempDir := t.TempDir()
+
+	params := shim.Params{BinaryPath: filepath.Join(tempDir, "moved", "git-hooks"), Version: "v1.0.0"}
+	require.NoError(t, shim.Write(tempDir, "pre-commit", params))
+
+	cmd := exec.Command(filepath.Join(tempDir, "pre-commit"))
+	cmd.Env = append(os.Environ(), "PATH=/usr/bin:/bin")
+	output, err := cmd.CombinedOutput()
+	require.Error(t, err)
+	require.Contains(t, string(output), "git-hooks config --repair")
+}
+
+func TestShim_QuotesPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	// Each of these would break out of a double-quoted shell string
+	dir := filepath.Join(tempDir, "it's \"$(touch pwned)\" `touch pwned` $HOME")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	binary := filepath.Join(dir, "git-hooks")
+	require.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\necho \"$GIT_HOOKS_HOME|$GIT_HOOKS_SYSTEM_DIR\"\n"), 0o755))
+	params := shim.Params{BinaryPath: binary, Version: "v1.0.0", Home: filepath.Join(dir, "home"), SystemDir: filepath.Join(dir, "system")}
+	require.NoError(t, shim.Write(tempDir, "pre-commit", params))
+
+	cmd := exec.Command(filepath.Join(tempDir, "pre-commit"))
+	cmd.Dir = tempDir
+	cmd.Env = append(os.Environ(), "GIT_HOOKS_HOME=")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	require.Equal(t, params.Home+"|"+params.SystemDir, strings.TrimSpace(string(output)))
+	require.NoFileExists(t, filepath.Join(tempDir, "pwned"))
+
+	t.Log("The quoted values are recovered, the shim stays pristine")
+	change, err := shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUnchanged, change.Action)
+	params.Version = "v1.1.0"
+	change, err = shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUpdated, change.Action)
+	require.Empty(t, change.MigratedTo)
+
+	require.Error(t, shim.Write(tempDir, "pre-commit", shim.Params{BinaryPath: "/bin/git-hooks\nrm -rf ~"}))
+}
+
+func TestInstall_UpdatesDoubleQuotedShim(t *testing.T) {
+	tempDir := t.TempDir()
+	v1 := `#!/bin/sh
+# Generated by git-hooks, do not edit. Add scripts to pre-commit.d/ instead.
+# git-hooks-version: v1.0.0
+# git-hooks-binary: /usr/local/bin/git-hooks
+GIT_HOOKS_BIN="/usr/local/bin/git-hooks"
+if [ ! -x "$GIT_HOOKS_BIN" ]; then
+    # The binary moved (e.g. after an upgrade), fall back to PATH
+    GIT_HOOKS_BIN=$(command -v git-hooks)
+fi
+if [ -z "$GIT_HOOKS_BIN" ]; then
+    echo "git-hooks: binary not found at /usr/local/bin/git-hooks or in PATH, run 'git-hooks config --repair'" >&2
+    exit 127
+fi
+GIT_HOOKS_HOME="${GIT_HOOKS_HOME:-/home/user/.git-hooks}"
+export GIT_HOOKS_HOME
+GIT_HOOKS_SHIM="$0"
+export GIT_HOOKS_SHIM
+exec "$GIT_HOOKS_BIN" hook pre-commit "$@"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pre-commit"), []byte(v1), 0o755))
+
+	params := shim.Params{BinaryPath: "/usr/local/bin/git-hooks", Version: "v1.1.0", Home: "/home/user/.git-hooks"}
+	change, err := shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUpdated, change.Action)
+	require.Empty(t, change.MigratedTo, "shims from before single-quoting are not backed up")
+	content, err := os.ReadFile(filepath.Join(tempDir, "pre-commit"))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "GIT_HOOKS_HOME='/home/user/.git-hooks'")
+}
+
+func TestInstall_Idempotent(t *testing.T) {
+	tempDir := t.TempDir()
+	params := shim.Params{BinaryPath: "/usr/local/bin/git-hooks", Version: "v1.0.0"}
+
+	change, err := shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionCreated, change.Action)
+
+	change, err = shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUnchanged, change.Action)
+
+	params.Version = "v1.1.0"
+	change, err = shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUpdated, change.Action)
+	require.Empty(t, change.MigratedTo)
+
+	t.Log("No temporary files are left behind")
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestInstall_MigratesUserScript(t *testing.T) {
+	tempDir := t.TempDir()
+	params := shim.Params{BinaryPath: "/usr/local/bin/git-hooks", Version: "v1.0.0"}
+
+	userScript := "#!/bin/sh\nnpm test\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pre-commit"), []byte(userScript), 0o755))
+
+	change, err := shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionCreated, change.Action)
+	require.Equal(t, filepath.Join(tempDir, "pre-commit.d", "00-migrated"), change.MigratedTo)
+
+	content, err := os.ReadFile(change.MigratedTo)
+	require.NoError(t, err)
+	require.Equal(t, userScript, string(content))
+
+	_, err = shim.Read(filepath.Join(tempDir, "pre-commit"))
+	require.NoError(t, err)
+
+	t.Log("A second user script goes to the backup folder when 00-migrated is taken")
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pre-commit"), []byte("#!/bin/sh\nmake lint\n"), 0o755))
+
+	change, err = shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(tempDir, ".backup"), filepath.Dir(change.MigratedTo))
+
+	content, err = os.ReadFile(change.MigratedTo)
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh\nmake lint\n", string(content))
+}
+
+func TestInstall_BacksUpEditedShim(t *testing.T) {
+	tempDir := t.TempDir()
+	params := shim.Params{BinaryPath: "/usr/local/bin/git-hooks", Version: "v1.0.0", Home: "/home/user/.git-hooks"}
+
+	_, err := shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+
+	t.Log("Shims written by older versions are updated in place")
+	params.Version = "v1.1.0"
+	change, err := shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUpdated, change.Action)
+	require.Empty(t, change.MigratedTo)
+
+	legacy := "#!/bin/sh\n# Run git-hooks with the full path\n\"/usr/local/bin/git-hooks\" hook pre-commit \"$@\""
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pre-commit"), []byte(legacy), 0o755))
+	change, err = shim.Install(tempDir, "pre-commit", params)
+	require.NoError(t, err)
+	require.Equal(t, shim.ActionUpdated, change.Action)
+	require.Empty(t, change.MigratedTo)
+
+	t.Log("Edited shims are backed up, never run from pre-commit.d")
+	var backups []string
+	for _, edit := range []string{"make lint\n", "make test\n"} {
+		content, err := os.ReadFile(filepath.Join(tempDir, "pre-commit"))
+		require.NoError(t, err)
+		edited := strings.Replace(string(content), "GIT_HOOKS_SHIM=", edit+"GIT_HOOKS_SHIM=", 1)
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "pre-commit"), []byte(edited), 0o755))
+
+		change, err = shim.Install(tempDir, "pre-commit", params)
+		require.NoError(t, err)
+		require.Equal(t, shim.ActionCreated, change.Action)
+		require.Equal(t, filepath.Join(tempDir, ".backup"), filepath.Dir(change.MigratedTo))
+		backup, err := os.ReadFile(change.MigratedTo)
+		require.NoError(t, err)
+		require.Equal(t, edited, string(backup))
+		backups = append(backups, change.MigratedTo)
+	}
+	require.NotEqual(t, backups[0], backups[1], "backups within the same second are kept apart")
+	require.NoDirExists(t, filepath.Join(tempDir, "pre-commit.d"))
+}
@@ -0,0 +1,313 @@
+package trailers
+
+import (
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // Change-Id is an identifier, not a signature
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+	"github.com/rudderlabs/git-hooks/internal/repoconfig"
+)
+
+// Policy decides what happens when the message already has the trailer
+type Policy string
+
+const (
+	// PolicyIfMissing leaves an existing trailer with the same key alone
+	PolicyIfMissing Policy = "if-missing"
+	// PolicyReplace removes existing trailers with the same key
+	PolicyReplace Policy = "replace"
+	// PolicyAppend adds the trailer unless the same key and value are there
+	PolicyAppend Policy = "append"
+)
+
+// Hooks are the hooks trailers can be added in
+var Hooks = []string{"commit-msg", "prepare-commit-msg"}
+
+var configPattern = `^githooks\.trailer\..+\.(key|value|hook|policy)$`
+
+// Spec declares a trailer added to commit messages
+type Spec struct {
+	Name string
+	Key  string
+	// Value is a text/template rendered with Data
+	Value string
+	// Hooks the trailer is added in, commit-msg when empty
+	Hooks []string
+	// Policy is if-missing when empty
+	Policy Policy
+	// Vars are extra template variables, e.g. set by built-in trailers
+	Vars map[string]string
+}
+
+// Data are the variables available to trailer templates
+type Data struct {
+	Hook   string
+	Branch string
+	User   User
+	Vars   map[string]string
+}
+
+// User is the committer identity from git config
+type User struct {
+	Name  string
+	Email string
+}
+
+func (s Spec) runsIn(hook string) bool {
+	if len(s.Hooks) == 0 {
+		return hook == "commit-msg"
+	}
+	return slices.Contains(s.Hooks, hook)
+}
+
+func parsePolicy(value string) (Policy, error) {
+	switch policy := Policy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return PolicyIfMissing, nil
+	case PolicyIfMissing, PolicyReplace, PolicyAppend:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown trailer policy %q, expected if-missing, replace or append", value)
+	}
+}
+
+// LoadSpecs returns the built-in trailers in defaults and the trailers
+// declared as `githooks.trailer.<name>.*` in git config, adjusted by the
+// trailers section of .git-hooks.yaml in repoDir. Config and
+// .git-hooks.yaml adjust a built-in trailer by its name. Trailers left
+// without a value are dropped, they only adjust built-in trailers that
+// aren't loaded.
+func LoadSpecs(repoDir string, defaults ...Spec) ([]Spec, error) {
+	pairs, err := gitconfig.GetRegexp(repoDir, gitconfig.ScopeDefault, configPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	fields := map[string]*repoconfig.Trailer{}
+	vars := map[string]map[string]string{}
+	for _, d := range defaults {
+		fields[d.Name] = &repoconfig.Trailer{Key: d.Key, Value: d.Value, Hook: strings.Join(d.Hooks, " "), Policy: string(d.Policy)}
+		vars[d.Name] = d.Vars
+		names = append(names, d.Name)
+	}
+	for _, pair := range pairs {
+		rest := strings.TrimPrefix(pair.Key, "githooks.trailer.")
+		i := strings.LastIndex(rest, ".")
+		name, field := rest[:i], rest[i+1:]
+		if fields[name] == nil {
+			fields[name] = &repoconfig.Trailer{}
+			names = append(names, name)
+		}
+		// Later scopes override earlier ones
+		switch field {
+		case "key":
+			fields[name].Key = pair.Value
+		case "value":
+			fields[name].Value = pair.Value
+		case "hook":
+			fields[name].Hook = pair.Value
+		case "policy":
+			fields[name].Policy = pair.Value
+		}
+	}
+
+	cfg, err := repoconfig.Load(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	repoNames := make([]string, 0, len(cfg.Trailers))
+	for name := range cfg.Trailers {
+		repoNames = append(repoNames, name)
+	}
+	sort.Strings(repoNames)
+	for _, name := range repoNames {
+		repo := cfg.Trailers[name]
+		t := fields[name]
+		if t == nil {
+			t = &repoconfig.Trailer{}
+			fields[name] = t
+			names = append(names, name)
+		}
+		t.Disable = repo.Disable
+		override(&t.Key, repo.Key)
+		override(&t.Value, repo.Value)
+		override(&t.Hook, repo.Hook)
+		override(&t.Policy, repo.Policy)
+	}
+
+	specs := make([]Spec, 0, len(names))
+	for _, name := range names {
+		t := fields[name]
+		if t.Disable || t.Value == "" {
+			continue
+		}
+		spec := Spec{Name: name, Key: t.Key, Value: t.Value, Hooks: strings.Fields(t.Hook), Vars: vars[name]}
+		if spec.Key == "" {
+			spec.Key = name
+		}
+		if len(spec.Hooks) == 0 {
+			spec.Hooks = []string{"commit-msg"}
+		}
+		if spec.Policy, err = parsePolicy(t.Policy); err != nil {
+			return nil, fmt.Errorf("trailer %s: %w", name, err)
+		}
+		for _, hook := range spec.Hooks {
+			if !slices.Contains(Hooks, hook) {
+				return nil, fmt.Errorf("trailer %s: cannot add trailers in %s, expected one of %s", name, hook, strings.Join(Hooks, ", "))
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// InjectFile adds the trailers of specs that run in hook to the commit
+// message in path. repoDir is where git config, the branch and the user are
+// read from. Trailers already present are reported on stdout.
+func InjectFile(path, hook, repoDir string, specs []Spec, stdout, stderr io.Writer) error {
+	var active []Spec
+	for _, spec := range specs {
+		if spec.runsIn(hook) {
+			active = append(active, spec)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading commit message: %w", err)
+	}
+	msg := Parse(string(data), Options{
+		CommentChar:  CommentChar(repoDir, string(data)),
+		Conventional: true,
+	})
+
+	base := templateData(repoDir, hook)
+	changed := false
+	for _, spec := range active {
+		if spec.Policy == "" {
+			spec.Policy = PolicyIfMissing
+		}
+		if spec.Policy == PolicyIfMissing && msg.Has(spec.Key) {
+			fmt.Fprintf(stdout, "%s trailer already present in commit message, skipping\n", spec.Key)
+			continue
+		}
+
+		d := base
+		d.Vars = spec.Vars
+		value, err := render(spec, d, string(data), stderr)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			fmt.Fprintf(stderr, "git-hooks: skipping trailer %s: empty value\n", spec.Name)
+			continue
+		}
+
+		t := Trailer{Key: spec.Key, Value: value}
+		switch spec.Policy {
+		case PolicyReplace:
+			msg.Replace(t)
+		case PolicyAppend:
+			if slices.Contains(msg.Get(spec.Key), value) {
+				continue
+			}
+			msg.Add(t)
+		default:
+			msg.Add(t)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(msg.String()), 0o644); err != nil {
+		return fmt.Errorf("writing commit message: %w", err)
+	}
+	return nil
+}
+
+func templateData(repoDir, hook string) Data {
+	d := Data{Hook: hook}
+	if output, err := exec.Command("git", "-C", repoDir, "symbolic-ref", "--short", "-q", "HEAD").Output(); err == nil {
+		d.Branch = strings.TrimSpace(string(output))
+	}
+	d.User.Name, _, _ = gitconfig.Get(repoDir, gitconfig.ScopeDefault, "user.name")
+	d.User.Email, _, _ = gitconfig.Get(repoDir, gitconfig.ScopeDefault, "user.email")
+	return d
+}
+
+// render executes the value template of spec. Values are single lines, so
+// surrounding whitespace and doubled spaces of empty variables are dropped.
+func render(spec Spec, d Data, msg string, stderr io.Writer) (string, error) {
+	tmpl, err := template.New(spec.Name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"version": func(tool string) string {
+			v := ToolVersion(tool)
+			if v == "" {
+				fmt.Fprintf(stderr, "git-hooks: failed to get %s version for trailer %s\n", tool, spec.Name)
+			}
+			return v
+		},
+		"env":      os.Getenv,
+		"changeId": func() string { return changeID(msg) },
+	}).Parse(spec.Value)
+	if err != nil {
+		return "", fmt.Errorf("parsing trailer %s: %w", spec.Name, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("rendering trailer %s: %w", spec.Name, err)
+	}
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
+
+// versionPattern finds the version number in `tool --version` output
+var versionPattern = regexp.MustCompile(`v?\d+(\.\d+)+\S*`)
+
+// ToolVersion returns the version printed by `tool version` or
+// `tool --version`, or an empty string when neither works
+func ToolVersion(tool string) string {
+	for _, arg := range []string{"version", "--version"} {
+		output, err := exec.Command(tool, arg).Output()
+		if err != nil {
+			continue
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+		if v := versionPattern.FindString(line); v != "" {
+			return v
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// changeID returns a Gerrit style Change-Id
+func changeID(msg string) string {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	sum := sha1.Sum(append(salt, msg...)) //nolint:gosec // see import
+	return "I" + hex.EncodeToString(sum[:])
+}
+
+func override(dst *string, src string) {
+	if src != "" {
+		*dst = src
+	}
+}
@@ -0,0 +1,162 @@
+package trailers_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/trailers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSpecs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := filepath.Join(home, "repo")
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	git(t, repo, "init")
+	git(t, repo, "config", "--global", "githooks.trailer.scanned-by.value", "gitleaks {{version \"gitleaks\"}}")
+	git(t, repo, "config", "--global", "githooks.trailer.sign.key", "Signed-off-by")
+	git(t, repo, "config", "--global", "githooks.trailer.sign.value", "{{.User.Name}} <{{.User.Email}}>")
+	git(t, repo, "config", "--global", "githooks.trailer.sign.policy", "append")
+	git(t, repo, "config", "--global", "githooks.trailer.ticket.value", "{{.Branch}}")
+	git(t, repo, "config", "--local", "githooks.trailer.sign.hook", "prepare-commit-msg")
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte(`
+trailers:
+  ticket:
+    disable: true
+  change-id:
+    key: Change-Id
+    value: "{{changeId}}"
+`), 0o644))
+
+	specs, err := trailers.LoadSpecs(repo)
+	require.NoError(t, err)
+	require.Equal(t, []trailers.Spec{
+		{Name: "scanned-by", Key: "scanned-by", Value: `gitleaks {{version "gitleaks"}}`, Hooks: []string{"commit-msg"}, Policy: trailers.PolicyIfMissing},
+		{Name: "sign", Key: "Signed-off-by", Value: "{{.User.Name}} <{{.User.Email}}>", Hooks: []string{"prepare-commit-msg"}, Policy: trailers.PolicyAppend},
+		{Name: "change-id", Key: "Change-Id", Value: "{{changeId}}", Hooks: []string{"commit-msg"}, Policy: trailers.PolicyIfMissing},
+	}, specs)
+
+	t.Log("Built-in trailers are adjusted by name, trailers without a value are dropped")
+	git(t, repo, "config", "--global", "githooks.trailer.builtin.policy", "replace")
+	git(t, repo, "config", "--global", "githooks.trailer.orphan.policy", "replace")
+	builtin := trailers.Spec{Name: "builtin", Key: "Built-in", Value: "{{.Vars.V}}", Hooks: []string{"commit-msg"}, Vars: map[string]string{"V": "1"}}
+	specs, err = trailers.LoadSpecs(repo, builtin)
+	require.NoError(t, err)
+	require.Equal(t, trailers.Spec{Name: "builtin", Key: "Built-in", Value: "{{.Vars.V}}", Hooks: []string{"commit-msg"}, Policy: trailers.PolicyReplace, Vars: map[string]string{"V": "1"}}, specs[0])
+	require.Len(t, specs, 4)
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git-hooks.yaml"), []byte("trailers:\n  builtin:\n    disable: true\n"), 0o644))
+	specs, err = trailers.LoadSpecs(repo, builtin)
+	require.NoError(t, err)
+	require.NotContains(t, names(specs), "builtin")
+
+	t.Log("Invalid policies are reported")
+	git(t, repo, "config", "--local", "githooks.trailer.sign.policy", "sometimes")
+	_, err = trailers.LoadSpecs(repo)
+	require.ErrorContains(t, err, "trailer sign: unknown trailer policy")
+}
+
+func TestInjectFile(t *testing.T) {
+	repo := t.TempDir()
+	git(t, repo, "init", "-b", "PROJ-12-login")
+	git(t, repo, "config", "user.name", "Jane Doe")
+	git(t, repo, "config", "user.email", "jane@example.com")
+
+	specs := []trailers.Spec{
+		{Name: "ticket", Key: "Refs", Value: "{{.Branch}}", Policy: trailers.PolicyIfMissing},
+		{Name: "sign", Key: "Signed-off-by", Value: "{{.User.Name}} <{{.User.Email}}>", Policy: trailers.PolicyAppend},
+		{Name: "hook", Key: "Hook", Value: "{{.Hook}} {{.Vars.Extra}}", Policy: trailers.PolicyReplace, Vars: map[string]string{"Extra": "x"}},
+		{Name: "prepare", Key: "Prepared", Value: "yes", Hooks: []string{"prepare-commit-msg"}},
+	}
+
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "adds all trailers",
+			message:  "feat: login\n",
+			expected: "feat: login\n\nRefs: PROJ-12-login\nSigned-off-by: Jane Doe <jane@example.com>\nHook: commit-msg x\n",
+		},
+		{
+			name:     "if-missing keeps, append skips duplicates, replace updates",
+			message:  "feat: login\n\nRefs: PROJ-1\nSigned-off-by: Jane Doe <jane@example.com>\nHook: old\n",
+			expected: "feat: login\n\nRefs: PROJ-1\nSigned-off-by: Jane Doe <jane@example.com>\nHook: commit-msg x\n",
+		},
+		{
+			name:     "append adds other values",
+			message:  "feat: login\n\nSigned-off-by: John Roe <john@example.com>\nRefs: PROJ-1\nHook: commit-msg x\n",
+			expected: "feat: login\n\nSigned-off-by: John Roe <john@example.com>\nRefs: PROJ-1\nSigned-off-by: Jane Doe <jane@example.com>\nHook: commit-msg x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+			require.NoError(t, os.WriteFile(path, []byte(tt.message), 0o644))
+
+			var stdout, stderr bytes.Buffer
+			require.NoError(t, trailers.InjectFile(path, "commit-msg", repo, specs, &stdout, &stderr))
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(content))
+		})
+	}
+}
+
+func TestInjectFile_Templates(t *testing.T) {
+	repo := t.TempDir()
+	git(t, repo, "init")
+
+	tool := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\n[ \"$1\" = --version ] && echo 'tool version 1.2.3 (abc)'\n"), 0o755))
+	t.Setenv("TRAILER_TEST", "from-env")
+
+	specs := []trailers.Spec{
+		{Name: "tool", Key: "Tool", Value: `tool {{version "` + tool + `"}}`},
+		{Name: "missing", Key: "Missing", Value: `missing {{version "/nonexistent"}}`},
+		{Name: "env", Key: "Env", Value: `{{env "TRAILER_TEST"}}`},
+		{Name: "empty", Key: "Empty", Value: `{{env "TRAILER_UNSET"}}`},
+		{Name: "change-id", Key: "Change-Id", Value: "{{changeId}}"},
+	}
+
+	path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	require.NoError(t, os.WriteFile(path, []byte("feat: x\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, trailers.InjectFile(path, "commit-msg", repo, specs, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "failed to get /nonexistent version")
+	require.Contains(t, stderr.String(), "skipping trailer empty: empty value")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	msg := trailers.Parse(string(content), trailers.Options{})
+	require.Equal(t, []string{"tool 1.2.3"}, msg.Get("Tool"))
+	require.Equal(t, []string{"missing"}, msg.Get("Missing"))
+	require.Equal(t, []string{"from-env"}, msg.Get("Env"))
+	require.False(t, msg.Has("Empty"))
+	require.Regexp(t, `^I[0-9a-f]{40}$`, msg.Get("Change-Id")[0])
+
+	t.Log("Running again keeps the Change-Id")
+	require.NoError(t, trailers.InjectFile(path, "commit-msg", repo, specs, &stdout, &stderr))
+	again, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(content), string(again))
+}
+
+func names(specs []trailers.Spec) []string {
+	var out []string
+	for _, spec := range specs {
+		out = append(out, spec.Name)
+	}
+	return out
+}
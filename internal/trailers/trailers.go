@@ -0,0 +1,356 @@
+package trailers
+
+import (
+	"strings"
+
+	"github.com/rudderlabs/git-hooks/internal/gitconfig"
+)
+
+// DefaultCommentChar is git's default core.commentChar
+const DefaultCommentChar = "#"
+
+// autoCommentChars are the candidates git picks from with core.commentChar=auto
+const autoCommentChars = "#;@!$%^&|:"
+
+// gitPrefixes are trailers git itself generates. A block containing one only
+// needs 25% trailer lines, like in git.
+var gitPrefixes = []string{"Signed-off-by: ", "(cherry picked from commit "}
+
+// Options control how trailers are recognised
+type Options struct {
+	// CommentChar starts comment lines, see core.commentChar
+	CommentChar string
+	// Separators are the characters that may separate a key from its value,
+	// see trailer.separators. The first is used when adding trailers.
+	Separators string
+	// Conventional also accepts Conventional Commits footers: the
+	// `BREAKING CHANGE` key and `#` as separator, e.g. `Fixes #123`
+	Conventional bool
+}
+
+// Trailer is a key-value line in the trailer block. Folded values keep their
+// line breaks and indentation.
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+func (t Trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// Message is a commit message split around its trailer block
+type Message struct {
+	opts Options
+	// head is everything before the trailer block, or before the tail when
+	// there is no block
+	head []string
+	// block holds the trailer block lines
+	block []string
+	// tail holds trailing blank and comment lines and everything from the
+	// scissors line on, which git strips from the message
+	tail []string
+	// newline is set when the message ended with a newline
+	newline bool
+}
+
+// Parse splits msg with `git interpret-trailers` semantics
+func Parse(msg string, opts Options) *Message {
+	if opts.CommentChar == "" {
+		opts.CommentChar = DefaultCommentChar
+	}
+	if opts.Separators == "" {
+		opts.Separators = ":"
+	}
+	if opts.Conventional && !strings.Contains(opts.Separators, "#") {
+		opts.Separators += "#"
+	}
+
+	m := &Message{opts: opts, newline: strings.HasSuffix(msg, "\n")}
+	lines := strings.Split(strings.TrimSuffix(msg, "\n"), "\n")
+	if msg == "" {
+		lines = nil
+	}
+
+	// Everything from the scissors line on is cut by git
+	end := len(lines)
+	for i, line := range lines {
+		if m.isScissors(line) {
+			end = i
+			break
+		}
+	}
+	// Trailing comments and blank lines aren't part of the message either
+	for end > 0 && (m.isComment(lines[end-1]) || isBlank(lines[end-1])) {
+		end--
+	}
+
+	start := m.blockStart(lines[:end])
+	// Copy the parts so Add can't overwrite the ones after them
+	m.head = append([]string(nil), lines[:start]...)
+	m.block = append([]string(nil), lines[start:end]...)
+	m.tail = append([]string(nil), lines[end:]...)
+	return m
+}
+
+// blockStart returns the index of the first trailer block line, or
+// len(lines) when the last paragraph isn't a trailer block
+func (m *Message) blockStart(lines []string) int {
+	// The first paragraph is the title and cannot be trailers
+	endOfTitle := len(lines)
+	for i, line := range lines {
+		if m.isComment(line) {
+			continue
+		}
+		if isBlank(line) {
+			endOfTitle = i
+			break
+		}
+	}
+
+	var recognized bool
+	var trailerLines, nonTrailerLines, continuationLines int
+	isBlock := func() bool {
+		return trailerLines > 0 && nonTrailerLines == 0 ||
+			recognized && trailerLines*3 >= nonTrailerLines
+	}
+
+	for i := len(lines) - 1; i >= endOfTitle; i-- {
+		line := lines[i]
+		switch {
+		case m.isComment(line):
+			continue
+		case isBlank(line):
+			// The blank line before the last paragraph
+			if isBlock() {
+				return i + 1
+			}
+			return len(lines)
+		case line[0] == ' ' || line[0] == '\t':
+			continuationLines++
+			continue
+		}
+
+		if m.hasGitPrefix(line) {
+			recognized = true
+			trailerLines += 1 + continuationLines
+		} else if m.separator(line) >= 1 {
+			trailerLines += 1 + continuationLines
+		} else {
+			nonTrailerLines += 1 + continuationLines
+		}
+		continuationLines = 0
+	}
+	return len(lines)
+}
+
+// separator returns the position of the separator in a trailer line, or -1,
+// following git's find_separator
+func (m *Message) separator(line string) int {
+	if m.opts.Conventional {
+		for _, key := range []string{"BREAKING CHANGE", "BREAKING-CHANGE"} {
+			if rest, ok := strings.CutPrefix(line, key); ok && rest != "" && strings.ContainsRune(m.opts.Separators, rune(rest[0])) {
+				return len(key)
+			}
+		}
+	}
+
+	whitespace := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if strings.IndexByte(m.opts.Separators, c) >= 0 {
+			return i
+		}
+		if !whitespace && (isAlnum(c) || c == '-') {
+			continue
+		}
+		if i > 0 && (c == ' ' || c == '\t') {
+			whitespace = true
+			continue
+		}
+		break
+	}
+	return -1
+}
+
+func (m *Message) hasGitPrefix(line string) bool {
+	for _, prefix := range gitPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Message) isComment(line string) bool {
+	return strings.HasPrefix(line, m.opts.CommentChar)
+}
+
+// isScissors reports whether line is the cut line `git commit --verbose`
+// puts above the diff, e.g. `# ------------------------ >8 ------------------------`
+func (m *Message) isScissors(line string) bool {
+	rest, ok := strings.CutPrefix(line, m.opts.CommentChar)
+	if !ok {
+		return false
+	}
+	dashes := strings.Count(rest, "-")
+	rest = strings.NewReplacer("-", "", " ", "").Replace(rest)
+	return dashes >= 8 && (rest == ">8" || rest == "8<")
+}
+
+// Trailers returns the trailers in the trailer block
+func (m *Message) Trailers() []Trailer {
+	var trailers []Trailer
+	for _, line := range m.block {
+		switch {
+		case m.isComment(line):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			if len(trailers) > 0 {
+				trailers[len(trailers)-1].Value += "\n" + line
+			}
+			continue
+		}
+
+		if i := m.separator(line); i >= 1 {
+			value := line[i+1:]
+			if line[i] == '#' {
+				// Keep the issue reference of `Fixes #123`
+				value = line[i:]
+			}
+			trailers = append(trailers, Trailer{
+				Key:   strings.TrimSpace(line[:i]),
+				Value: strings.TrimSpace(value),
+			})
+		} else {
+			// Non-trailer lines of a mixed block
+			trailers = append(trailers, Trailer{Value: line})
+		}
+	}
+	return trailers
+}
+
+// Get returns the values of the trailers with key, compared case
+// insensitively like git does
+func (m *Message) Get(key string) []string {
+	var values []string
+	for _, t := range m.Trailers() {
+		if t.Key != "" && strings.EqualFold(t.Key, key) {
+			values = append(values, t.Value)
+		}
+	}
+	return values
+}
+
+// Has reports whether the trailer block contains key
+func (m *Message) Has(key string) bool {
+	return len(m.Get(key)) > 0
+}
+
+// Add appends a trailer to the trailer block, starting a block when there is
+// none
+func (m *Message) Add(t Trailer) {
+	if len(m.block) == 0 && len(m.head) > 0 && !isBlank(m.head[len(m.head)-1]) {
+		// Separate the new block from the message like git does
+		m.head = append(m.head, "")
+	}
+	m.block = append(m.block, m.format(t))
+}
+
+// Replace removes the trailers with the key of t and appends t
+func (m *Message) Replace(t Trailer) {
+	m.Remove(t.Key)
+	m.Add(t)
+}
+
+// Remove drops the trailers with key, including their folded lines
+func (m *Message) Remove(key string) {
+	m.RemoveFunc(key, func(string) bool { return true })
+}
+
+// RemoveFunc drops the trailers with key whose value, without folded lines,
+// match reports true for
+func (m *Message) RemoveFunc(key string, match func(value string) bool) {
+	var block []string
+	removing := false
+	for _, line := range m.block {
+		if removing && line != "" && (line[0] == ' ' || line[0] == '\t') {
+			continue
+		}
+		removing = false
+		if i := m.separator(line); i >= 1 && !m.isComment(line) && strings.EqualFold(strings.TrimSpace(line[:i]), key) &&
+			match(strings.TrimSpace(line[i+1:])) {
+			removing = true
+			continue
+		}
+		block = append(block, line)
+	}
+	m.block = block
+
+	// Drop the separator line of a block that is now empty
+	if len(m.block) == 0 && len(m.head) > 0 && isBlank(m.head[len(m.head)-1]) {
+		m.head = m.head[:len(m.head)-1]
+	}
+}
+
+func (m *Message) format(t Trailer) string {
+	return t.Key + string(m.opts.Separators[0]) + " " + t.Value
+}
+
+// String returns the message with the edited trailer block
+func (m *Message) String() string {
+	lines := make([]string, 0, len(m.head)+len(m.block)+len(m.tail))
+	lines = append(lines, m.head...)
+	lines = append(lines, m.block...)
+	lines = append(lines, m.tail...)
+
+	msg := strings.Join(lines, "\n")
+	if m.newline {
+		msg += "\n"
+	}
+	return msg
+}
+
+// Committed returns the message as git commits it: without the tail, and
+// with comment lines blanked so line numbers still match the original
+func (m *Message) Committed() string {
+	lines := make([]string, 0, len(m.head)+len(m.block))
+	for _, line := range append(append([]string(nil), m.head...), m.block...) {
+		if m.isComment(line) {
+			line = ""
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// CommentChar returns core.commentChar for the repository at dir. With
+// `auto` git picks a character not used by the message, which is detected
+// from its scissors or instruction lines.
+func CommentChar(dir, msg string) string {
+	value, ok, err := gitconfig.Get(dir, gitconfig.ScopeDefault, "core.commentChar")
+	if err != nil || !ok || value == "" {
+		return DefaultCommentChar
+	}
+	if value != "auto" {
+		return value
+	}
+
+	for _, c := range autoCommentChars {
+		m := &Message{opts: Options{CommentChar: string(c)}}
+		for _, line := range strings.Split(msg, "\n") {
+			if m.isScissors(line) || strings.HasPrefix(line, string(c)+" Please enter the commit message") {
+				return string(c)
+			}
+		}
+	}
+	return DefaultCommentChar
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,185 @@
+package trailers_test
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rudderlabs/git-hooks/internal/trailers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdd(t *testing.T) {
+	added := trailers.Trailer{Key: "Scanned-by", Value: "gitleaks"}
+
+	tests := []struct {
+		name     string
+		opts     trailers.Options
+		message  string
+		expected string
+	}{
+		{
+			name:     "title only",
+			message:  "feat: add feature\n",
+			expected: "feat: add feature\n\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "title with trailer syntax is not a trailer block",
+			message:  "Fixes: the title\n",
+			expected: "Fixes: the title\n\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "existing trailers",
+			message:  "feat: add feature\n\nbody\n\nSigned-off-by: A <a@example.com>\n",
+			expected: "feat: add feature\n\nbody\n\nSigned-off-by: A <a@example.com>\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "mixed block with a git trailer",
+			message:  "feat: add feature\n\nReviewed on the list\nSigned-off-by: A <a@example.com>\n",
+			expected: "feat: add feature\n\nReviewed on the list\nSigned-off-by: A <a@example.com>\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "mixed block without a git trailer",
+			message:  "feat: add feature\n\nReviewed on the list\nAcked-by: A <a@example.com>\n",
+			expected: "feat: add feature\n\nReviewed on the list\nAcked-by: A <a@example.com>\n\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "folded trailer",
+			message:  "feat: add feature\n\nNote: a long\n  folded value\n",
+			expected: "feat: add feature\n\nNote: a long\n  folded value\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "trailers before the commit template comments",
+			message:  "feat: add feature\n\nFixes: #1\n\n# Please enter the commit message for your changes.\n#\n# On branch main\n",
+			expected: "feat: add feature\n\nFixes: #1\nScanned-by: gitleaks\n\n# Please enter the commit message for your changes.\n#\n# On branch main\n",
+		},
+		{
+			name:     "comment lines inside the block are ignored",
+			message:  "feat: add feature\n\nFixes: #1\n# a comment\nRefs: #2\n",
+			expected: "feat: add feature\n\nFixes: #1\n# a comment\nRefs: #2\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "scissors line cuts the diff",
+			message:  "feat: add feature\n\n# ------------------------ >8 ------------------------\n# Do not modify or remove the line above.\ndiff --git a/x b/x\n+Key: value\n",
+			expected: "feat: add feature\n\nScanned-by: gitleaks\n\n# ------------------------ >8 ------------------------\n# Do not modify or remove the line above.\ndiff --git a/x b/x\n+Key: value\n",
+		},
+		{
+			name:     "custom comment char",
+			opts:     trailers.Options{CommentChar: ";"},
+			message:  "feat: add feature\n\n; Key: not a trailer\n; ------------------------ >8 ------------------------\n",
+			expected: "feat: add feature\n\nScanned-by: gitleaks\n\n; Key: not a trailer\n; ------------------------ >8 ------------------------\n",
+		},
+		{
+			name:     "hash lines are content with another comment char",
+			opts:     trailers.Options{CommentChar: ";"},
+			message:  "feat: add feature\n\n# Heading\n",
+			expected: "feat: add feature\n\n# Heading\n\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "conventional footers",
+			opts:     trailers.Options{Conventional: true},
+			message:  "feat!: drop v1\n\nBREAKING CHANGE: the v1 API\n  is gone\nCloses #12\n",
+			expected: "feat!: drop v1\n\nBREAKING CHANGE: the v1 API\n  is gone\nCloses #12\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "conventional footers are plain text for git",
+			message:  "feat!: drop v1\n\nBREAKING CHANGE: the v1 API\n",
+			expected: "feat!: drop v1\n\nBREAKING CHANGE: the v1 API\n\nScanned-by: gitleaks\n",
+		},
+		{
+			name:     "empty message",
+			message:  "",
+			expected: "Scanned-by: gitleaks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := trailers.Parse(tt.message, tt.opts)
+			msg.Add(added)
+			require.Equal(t, tt.expected, msg.String())
+		})
+	}
+}
+
+func TestAdd_MatchesInterpretTrailers(t *testing.T) {
+	messages := []string{
+		"feat: add feature\n",
+		"feat: add feature\n\nbody\n",
+		"feat: add feature\n\nFixes: #1\n",
+		"feat: add feature\n\nbody: with a colon in prose\n",
+		"feat: add feature\n\nNote: a long\n  folded value\n",
+		"feat: add feature\n\nReviewed on the list\nSigned-off-by: A <a@example.com>\n",
+		"feat: add feature\n\nFixes: #1\n\n# comment\n",
+		"feat: add feature\n\n# ------------------------ >8 ------------------------\n+Key: value\n",
+	}
+
+	for _, message := range messages {
+		cmd := exec.Command("git", "interpret-trailers", "--trailer", "Scanned-by: gitleaks")
+		cmd.Stdin = strings.NewReader(message)
+		expected, err := cmd.Output()
+		require.NoError(t, err)
+
+		msg := trailers.Parse(message, trailers.Options{})
+		msg.Add(trailers.Trailer{Key: "Scanned-by", Value: "gitleaks"})
+		require.Equal(t, string(expected), msg.String(), "message %q", message)
+	}
+}
+
+func TestTrailers(t *testing.T) {
+	msg := trailers.Parse("feat: x\n\nbody\n\nFixes #1\nNote: a\n  b\nscanned-by: gitleaks v8\n", trailers.Options{Conventional: true})
+
+	require.Equal(t, []trailers.Trailer{
+		{Key: "Fixes", Value: "#1"},
+		{Key: "Note", Value: "a\n  b"},
+		{Key: "scanned-by", Value: "gitleaks v8"},
+	}, msg.Trailers())
+	require.True(t, msg.Has("Scanned-by"), "keys are case insensitive")
+	require.Equal(t, []string{"gitleaks v8"}, msg.Get("SCANNED-BY"))
+	require.False(t, msg.Has("body"))
+}
+
+func TestReplaceAndRemove(t *testing.T) {
+	msg := trailers.Parse("feat: x\n\nNote: a\n  b\nScanned-by: gitleaks v7\n", trailers.Options{})
+	msg.Replace(trailers.Trailer{Key: "Scanned-by", Value: "gitleaks v8"})
+	require.Equal(t, "feat: x\n\nNote: a\n  b\nScanned-by: gitleaks v8\n", msg.String())
+
+	msg.Remove("note")
+	require.Equal(t, "feat: x\n\nScanned-by: gitleaks v8\n", msg.String())
+
+	msg.Remove("Scanned-by")
+	require.Equal(t, "feat: x\n", msg.String())
+
+	msg = trailers.Parse("feat: x\n\nScanned-by: gitleaks v8\nScanned-by: other\n", trailers.Options{})
+	msg.RemoveFunc("scanned-by", func(value string) bool { return value == "other" })
+	require.Equal(t, "feat: x\n\nScanned-by: gitleaks v8\n", msg.String())
+}
+
+func TestCommitted(t *testing.T) {
+	msg := trailers.Parse("feat: x\n# comment\nbody\n\nFixes: #1\n\n# Please enter the commit message\n# ------------------------ >8 ------------------------\ndiff\n", trailers.Options{})
+	require.Equal(t, "feat: x\n\nbody\n\nFixes: #1\n", msg.Committed())
+}
+
+func TestCommentChar(t *testing.T) {
+	dir := t.TempDir()
+	git(t, dir, "init")
+	require.Equal(t, "#", trailers.CommentChar(dir, ""))
+
+	git(t, dir, "config", "core.commentChar", ";")
+	require.Equal(t, ";", trailers.CommentChar(dir, ""))
+
+	git(t, dir, "config", "core.commentChar", "auto")
+	require.Equal(t, "@", trailers.CommentChar(dir, "# title\n\n@ Please enter the commit message for your changes.\n"))
+	require.Equal(t, "#", trailers.CommentChar(dir, "title\n"))
+}
+
+// Helper functions
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+}
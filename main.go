@@ -5,13 +5,16 @@ import (
 	"os"
 
 	"github.com/rudderlabs/git-hooks/commands"
+	"github.com/rudderlabs/git-hooks/internal/buildinfo"
 	"github.com/urfave/cli/v2"
 )
 
 func main() {
 	app := &cli.App{
-		Name:  "git-hooks",
-		Usage: "Manage and execute Git hooks",
+		Name:    "git-hooks",
+		Usage:   "Manage and execute Git hooks",
+		Version: buildinfo.Version(),
+		Flags:   []cli.Flag{commands.HomeFlag},
 		Commands: []*cli.Command{
 			commands.Config,
 			commands.Implode,
@@ -19,6 +22,11 @@ func main() {
 			commands.Add,
 			commands.Remove,
 			commands.ScanLocal,
+			commands.Doctor,
+			commands.Migrate,
+			commands.Gitleaks,
+			commands.VerifyScanned,
+			commands.VerifyAttestation,
 		},
 	}
 